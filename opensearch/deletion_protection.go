@@ -0,0 +1,107 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+// addDeletionProtectionSchema adds deletion_protection and force_destroy to the schema of a saved object resource.
+func addDeletionProtectionSchema(r *schema.Resource) *schema.Resource {
+	r.Schema["deletion_protection"] = &schema.Schema{
+		Description: "If set, the object is only deleted if no saved objects outside of Terraform reference it, those are listed in the error. Objects count as managed by Terraform if they carry the `managed_marker` of the provider, are in the namespace of `object_id_prefix` and `object_id_suffix` or are listed in `allowed_referrers`. Without `managed_marker` and ID namespace, objects of the Terraform state are only recognized if they are listed in `allowed_referrers`, so destroying the object together with the dashboards or visualizations using it fails otherwise. Use this e.g. for index patterns which unmanaged dashboards might use.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	}
+	r.Schema["allowed_referrers"] = &schema.Schema{
+		Description: "Saved objects which may still reference the object when it is deleted with `deletion_protection`, as `<type>/<obj_id>`, e.g. `dashboard/errors`. Only needed for objects managed by Terraform which the provider cannot recognize by the `managed_marker` or the ID namespace.",
+		Type:        schema.TypeSet,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+	}
+	r.Schema["force_destroy"] = &schema.Schema{
+		Description: "Deletes the object even if `deletion_protection` is set and other objects still reference it. Like `deletion_protection` this has to be applied before it has an effect on destroy.",
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+	}
+
+	return r
+}
+
+// checkInboundReferences refuses to delete obj from the tenant if it has deletion protection enabled and is
+// referenced by saved objects which are not managed by Terraform.
+func (c *OpensearchDashboardsClient) checkInboundReferences(ctx context.Context, d *schema.ResourceData, tenant string, obj saved_objects.ObjectKey) diag.Diagnostics {
+	if !d.Get("deletion_protection").(bool) || d.Get("force_destroy").(bool) {
+		return nil
+	}
+
+	namespaced := c.idNamespace.ApplyKey(obj)
+	referencing, err := c.SavedObjects.FindObjects(ctx, saved_objects.FindOptions{
+		Types:        saved_objects.ReferencingTypes,
+		HasReference: &namespaced,
+		Tenant:       tenant,
+	})
+	if err != nil {
+		return errorDiagnostics(err, "could not find objects referencing %s %q", obj.Type, obj.ID)
+	}
+
+	allowed := map[string]bool{}
+	for _, referrer := range d.Get("allowed_referrers").(*schema.Set).List() {
+		allowed[referrer.(string)] = true
+	}
+
+	var unmanaged []string
+	for _, ref := range referencing {
+		key := saved_objects.ObjectKey{Type: ref.Type, ID: c.idNamespace.Strip(ref.ID)}
+		if key == obj || allowed[key.Type+"/"+key.ID] || c.isManaged(ref) {
+			continue
+		}
+		description := fmt.Sprintf("%s %q", ref.Type, ref.ID)
+		if title, ok := ref.Attributes["title"].(string); ok && title != "" {
+			description += fmt.Sprintf(" (%s)", title)
+		}
+		unmanaged = append(unmanaged, description)
+	}
+	if len(unmanaged) == 0 {
+		return nil
+	}
+
+	summary := fmt.Sprintf("%s %q is still referenced by other saved objects", obj.Type, obj.ID)
+	if tenant != "" {
+		summary += fmt.Sprintf(" in tenant %q", tenant)
+	}
+
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  summary,
+		Detail:   fmt.Sprintf("deletion_protection is enabled and the following objects would break: %s. Remove the references or set force_destroy to delete it anyway.", strings.Join(unmanaged, ", ")),
+	}}
+}
+
+// isManaged reports whether the object is recognizable as managed by Terraform, because it carries the managed
+// marker or is in the ID namespace of the provider.
+func (c *OpensearchDashboardsClient) isManaged(obj saved_objects.SavedObjectOSD) bool {
+	return c.managedMarker.IsMarked(obj.Type, obj.Attributes) || c.idNamespace.Contains(obj.ID)
+}
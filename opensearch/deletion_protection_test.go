@@ -0,0 +1,122 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/fakeosd"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+func TestCheckInboundReferences(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		namespace  *saved_objects.IDNamespace
+		marker     *saved_objects.ManagedMarker
+		config     map[string]any
+		referrers  []fakeosd.Object
+		wantBroken []string
+	}{
+		{
+			desc:       "must refuse to delete objects referenced by unmanaged objects",
+			config:     map[string]any{"deletion_protection": true},
+			referrers:  []fakeosd.Object{{Type: "dashboard", ID: "other", Attributes: map[string]any{"title": "Other"}}},
+			wantBroken: []string{`dashboard "other" (Other)`},
+		},
+		{
+			desc:      "must delete objects without deletion protection",
+			config:    map[string]any{},
+			referrers: []fakeosd.Object{{Type: "dashboard", ID: "other"}},
+		},
+		{
+			desc:      "must delete objects with force_destroy",
+			config:    map[string]any{"deletion_protection": true, "force_destroy": true},
+			referrers: []fakeosd.Object{{Type: "dashboard", ID: "other"}},
+		},
+		{
+			desc:   "must allow referrers with the managed marker",
+			marker: &saved_objects.ManagedMarker{TitleSuffix: " [terraform]"},
+			config: map[string]any{"deletion_protection": true},
+			referrers: []fakeosd.Object{
+				{Type: "dashboard", ID: "marked", Attributes: map[string]any{"title": "Errors [terraform]"}},
+				{Type: "dashboard", ID: "other", Attributes: map[string]any{"title": "Errors"}},
+			},
+			wantBroken: []string{`dashboard "other"`},
+		},
+		{
+			desc:      "must allow referrers in the ID namespace",
+			namespace: &saved_objects.IDNamespace{Prefix: "staging-"},
+			config:    map[string]any{"deletion_protection": true},
+			referrers: []fakeosd.Object{
+				{Type: "dashboard", ID: "staging-errors"},
+				{Type: "dashboard", ID: "production-errors"},
+			},
+			wantBroken: []string{`dashboard "production-errors"`},
+		},
+		{
+			desc:   "must allow listed referrers",
+			config: map[string]any{"deletion_protection": true, "allowed_referrers": []any{"dashboard/listed"}},
+			referrers: []fakeosd.Object{
+				{Type: "dashboard", ID: "listed"},
+				{Type: "visualization", ID: "listed"},
+			},
+			wantBroken: []string{`visualization "listed"`},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := fakeosd.New(fakeosd.Options{})
+			defer s.Close()
+			pattern := tC.namespace.Apply("pattern")
+			s.Put("", fakeosd.Object{Type: "index-pattern", ID: pattern, Attributes: map[string]any{"title": "logs-*"}})
+			for _, referrer := range tC.referrers {
+				referrer.References = []fakeosd.Reference{{Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern", ID: pattern}}
+				s.Put("", referrer)
+			}
+
+			provider := saved_objects.NewSavedObjectsProvider(s.URL, http.DefaultClient, false)
+			provider.IDNamespace = tC.namespace
+			hc := &OpensearchDashboardsClient{SavedObjects: provider, managedMarker: tC.marker, idNamespace: tC.namespace}
+			d := schema.TestResourceDataRaw(t, resourceSavedObjects().Schema, tC.config)
+
+			diags := hc.checkInboundReferences(context.Background(), d, "", saved_objects.ObjectKey{Type: "index-pattern", ID: "pattern"})
+			if len(tC.wantBroken) == 0 {
+				if diags != nil {
+					t.Errorf("expected the object to be deleted but got %v", diags)
+				}
+				return
+			}
+			if !diags.HasError() {
+				t.Fatal("expected the deletion to be refused")
+			}
+			detail := diags[0].Detail
+			for _, broken := range tC.wantBroken {
+				if !strings.Contains(detail, broken) {
+					t.Errorf("expected %s to be listed but got %s", broken, detail)
+				}
+			}
+			if got := strings.Count(detail, `"`) / 2; got != len(tC.wantBroken) {
+				t.Errorf("expected %d objects to be listed but got %s", len(tC.wantBroken), detail)
+			}
+		})
+	}
+}
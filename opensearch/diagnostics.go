@@ -0,0 +1,57 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+)
+
+// errorDiagnostics translates an error returned by the API clients into diagnostics. Errors of the API
+// are summarized by their status, the response body goes into the detail.
+func errorDiagnostics(err error, summary string, args ...any) diag.Diagnostics {
+	if err == nil {
+		return nil
+	}
+	prefix := fmt.Sprintf(summary, args...)
+
+	var conflictErr *apierror.ConflictError
+	if errors.As(err, &conflictErr) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("%s: conflict, the object was modified concurrently or already exists", prefix),
+			Detail:   conflictErr.Error(),
+		}}
+	}
+
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("%s: %s '%s' failed with status %d", prefix, apiErr.Method, apiErr.URL, apiErr.StatusCode),
+			Detail:   apiErr.Body,
+		}}
+	}
+
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  fmt.Sprintf("%s: %v", prefix, err),
+	}}
+}
@@ -0,0 +1,105 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/status"
+)
+
+const skipHealthCheckHint = "Set skip_health_check to configure the provider without connection, e.g. for plans with -refresh=false."
+
+// healthCheck requests the status of OpenSearch Dashboards, so a wrong URL or missing permissions are reported once
+// with a hint instead of by every resource. It returns the detected version, nil if the check failed.
+func healthCheck(ctx context.Context, client status.Client, baseUrl string) (*status.Version, diag.Diagnostics) {
+	result, err := client.GetStatus(ctx)
+	if err != nil {
+		return nil, healthCheckDiagnostics(err, baseUrl)
+	}
+
+	tflog.Info(ctx, "connected to OpenSearch Dashboards", map[string]any{
+		"version":      result.Version.Number,
+		"build_number": result.Version.BuildNumber,
+		"state":        result.State(),
+	})
+
+	var diags diag.Diagnostics
+	if major := result.Version.Major(); major != 1 && major != 2 {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("OpenSearch Dashboards %s is not supported", result.Version),
+			Detail:   "The provider supports OpenSearch Dashboards 1.x and 2.x, other versions may behave differently.",
+		})
+	}
+	if state := result.State(); state != "green" {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("OpenSearch Dashboards at %s is in state %s", baseUrl, state),
+			Detail:   "Requests of the provider may fail until OpenSearch Dashboards is green again.",
+		})
+	}
+
+	return &result.Version, diags
+}
+
+// healthCheckDiagnostics explains why the status could not be requested.
+func healthCheckDiagnostics(err error, baseUrl string) diag.Diagnostics {
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return diag.Diagnostics{{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("authentication at OpenSearch Dashboards %s failed with status %d", baseUrl, apiErr.StatusCode),
+				Detail:   "Check the AWS credentials and region of the provider, their role needs access to OpenSearch Dashboards. Set disable_authentication for clusters without authentication.\n\n" + apiErr.Body,
+			}}
+		case http.StatusNotFound:
+			return diag.Diagnostics{{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("OpenSearch Dashboards not found at %s", baseUrl),
+				Detail:   fmt.Sprintf("GET '%s' failed with status 404. Check base_url and path_prefix, the default path_prefix '/_dashboards' is the one of AWS OpenSearch and has to be set to an empty string for OpenSearch Dashboards without prefix.", apiErr.URL),
+			}}
+		}
+
+		diags := errorDiagnostics(err, "could not check the status of OpenSearch Dashboards at %s", baseUrl)
+		diags[0].Detail += "\n\n" + skipHealthCheckHint
+		return diags
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("could not reach OpenSearch Dashboards at %s", baseUrl),
+			Detail:   fmt.Sprintf("%v\n\nCheck base_url and the network connection. %s", err, skipHealthCheckHint),
+		}}
+	}
+
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  fmt.Sprintf("no OpenSearch Dashboards found at %s", baseUrl),
+		Detail:   fmt.Sprintf("%v\n\nCheck base_url and path_prefix, the response may be the one of a proxy or a login page.", err),
+	}}
+}
@@ -0,0 +1,149 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/fakeosd"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/status"
+)
+
+func TestHealthCheck(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	login := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>Login</body></html>`))
+	}))
+	defer login.Close()
+
+	testCases := []struct {
+		desc        string
+		profile     fakeosd.Profile
+		fault       *fakeosd.Fault
+		baseUrl     func(s *fakeosd.Server) string
+		wantVersion string
+		wantSummary string
+		wantError   bool
+	}{
+		{
+			desc:        "must detect OpenSearch Dashboards 2.x",
+			profile:     fakeosd.Profile2,
+			wantVersion: fakeosd.Profile2.Version,
+		},
+		{
+			desc:        "must detect OpenSearch Dashboards 1.x",
+			profile:     fakeosd.Profile1,
+			wantVersion: fakeosd.Profile1.Version,
+		},
+		{
+			desc:        "must report failed authentication",
+			fault:       &fakeosd.Fault{Status: http.StatusForbidden},
+			wantSummary: "authentication at OpenSearch Dashboards",
+			wantError:   true,
+		},
+		{
+			desc:        "must report a wrong path prefix",
+			baseUrl:     func(s *fakeosd.Server) string { return s.URL + "/_dashboards" },
+			wantSummary: "OpenSearch Dashboards not found",
+			wantError:   true,
+		},
+		{
+			desc:        "must report other errors",
+			fault:       &fakeosd.Fault{Status: http.StatusServiceUnavailable},
+			wantSummary: "could not check the status of OpenSearch Dashboards",
+			wantError:   true,
+		},
+		{
+			desc:        "must report an unreachable OpenSearch Dashboards",
+			baseUrl:     func(*fakeosd.Server) string { return closed.URL },
+			wantSummary: "could not reach OpenSearch Dashboards",
+			wantError:   true,
+		},
+		{
+			desc:        "must report responses which are not from OpenSearch Dashboards",
+			baseUrl:     func(*fakeosd.Server) string { return login.URL },
+			wantSummary: "no OpenSearch Dashboards found",
+			wantError:   true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := fakeosd.New(fakeosd.Options{Profile: tC.profile})
+			defer s.Close()
+			if tC.fault != nil {
+				s.Inject(*tC.fault)
+			}
+			baseUrl := s.URL
+			if tC.baseUrl != nil {
+				baseUrl = tC.baseUrl(s)
+			}
+
+			version, diags := healthCheck(context.Background(), status.NewProvider(baseUrl, http.DefaultClient), baseUrl)
+			if diags.HasError() != tC.wantError {
+				t.Fatalf("expected error %v but got %v", tC.wantError, diags)
+			}
+			if tC.wantError {
+				if version != nil || !strings.Contains(diags[0].Summary, tC.wantSummary) {
+					t.Errorf("expected %q but got %v", tC.wantSummary, diags)
+				}
+				return
+			}
+			if version == nil || version.Number != tC.wantVersion {
+				t.Errorf("expected version %s but got %v", tC.wantVersion, version)
+			}
+		})
+	}
+}
+
+func TestHealthCheckWarnings(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		body        string
+		wantSummary string
+	}{
+		{
+			desc:        "must warn about unsupported versions",
+			body:        `{"version":{"number":"3.0.0"},"status":{"overall":{"state":"green"}}}`,
+			wantSummary: "OpenSearch Dashboards 3.0.0 is not supported",
+		},
+		{
+			desc:        "must warn about degraded states",
+			body:        `{"version":{"number":"2.11.0"},"status":{"overall":{"state":"red"}}}`,
+			wantSummary: "is in state red",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(tC.body))
+			}))
+			defer srv.Close()
+
+			version, diags := healthCheck(context.Background(), status.NewProvider(srv.URL, http.DefaultClient), srv.URL)
+			if version == nil || len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Summary, tC.wantSummary) {
+				t.Errorf("expected the warning %q but got %v %v", tC.wantSummary, version, diags)
+			}
+		})
+	}
+}
@@ -20,14 +20,21 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/advanced_settings"
 	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/default_index_pattern"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/har"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/httplog"
 	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
 	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/sigv4"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/status"
 )
 
 func Provider() *schema.Provider {
@@ -58,10 +65,101 @@ func Provider() *schema.Provider {
 				Default:     "/_dashboards",
 				Description: "prefix to be prepended to any path. The default is '/_dashboards' to prevent breaking change since this is needed for AWS Opensearch on which this provider was first used. You will want to set this to an empty string for development on a local Opensearch for example",
 			},
+			"managed_marker": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Marks dashboards, visualizations, searches and queries managed by Terraform, so they can be recognized in the UI. The suffixes are appended when an object is written and removed when it is read, so they never cause a diff. Configured titles and descriptions must not end with the suffixes. Index patterns are not marked, because their title is the pattern.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"title_suffix": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							AtLeastOneOf: []string{"managed_marker.0.title_suffix", "managed_marker.0.description_suffix"},
+							Description:  "Suffix appended to the title, e.g. ` [terraform]`.",
+						},
+						"description_suffix": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							AtLeastOneOf: []string{"managed_marker.0.title_suffix", "managed_marker.0.description_suffix"},
+							Description:  "Suffix appended to the description of objects which have one, e.g. ` Managed by Terraform, changes in the UI will be overwritten.`.",
+						},
+					},
+				},
+			},
+			"object_id_prefix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Prefix prepended to the ID of every saved object, e.g. `staging-`. References are prefixed as well, except those marked as `external`, which point to a shared object with the ID as configured. The prefix is removed when objects are read, so `obj_id` and references can be the same in all environments.",
+			},
+			"object_id_suffix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Suffix appended to the ID of every saved object. It is applied to references and removed on read like `object_id_prefix`.",
+			},
+			"validate_references": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      referenceValidationOff,
+				ValidateFunc: validation.StringInSlice([]string{referenceValidationOff, referenceValidationWarn, referenceValidationError}, false),
+				Description:  "Checks before a saved object is created or updated that all objects it references exist, using one `_bulk_get` request per object or the batched reads of `read_batch_window`. Missing objects are reported as warning with `warn` or fail the apply with `error`. References to saved objects which the provider has already planned to create in the same run are skipped. Terraform plans each resource right before it applies it, so a target whose resource is not a dependency of the referencing resource may not be planned yet; a dependency, e.g. by using the `obj_id` attribute of the resource as `id` of the reference, makes sure the target is created first. The default is `off`.",
+			},
+			"sensitive_attribute_paths": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Dotted paths of JSON attributes which are redacted from request and response bodies in the logs, e.g. `attributes.description`. The paths match at any depth of a body. Bodies are only logged at `TRACE` level, the credentials of requests are always redacted. The HTTP requests are logged in the subsystem `http`, whose level can be set with `TF_LOG_PROVIDER_OPENSEARCH_HTTP`.",
+			},
+			"har_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("OS_HAR_FILE", ""),
+				Description: "Path of a HAR 1.2 file to which all HTTP requests and responses of the provider are written, for debugging. The file can be opened in the developer tools of browsers, which can also copy single requests as curl command. Requests are recorded before they are signed, so they carry no AWS credentials, credential headers are redacted and so are the `sensitive_attribute_paths` in the bodies. Requests of further runs are appended to an existing HAR file, other existing files are not overwritten. Can be set with `OS_HAR_FILE`.",
+			},
+			"skip_health_check": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("OS_SKIP_HEALTH_CHECK", false),
+				Description: "When the provider is configured, it requests `/api/status` to report an unreachable OpenSearch Dashboards, failed authentication or a wrong `path_prefix` before any resource is read, and to detect the version of OpenSearch Dashboards. Set to `true` to configure the provider without connection, e.g. for plans with `-refresh=false`. Can be set with `OS_SKIP_HEALTH_CHECK`.",
+			},
+			"read_batch_window": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "0s",
+				ValidateFunc: validateDuration,
+				Description:  "Saved objects read within this duration of each other, e.g. `10ms` during a plan, are fetched with a single `_bulk_get` request per tenant, which speeds up large plans and avoids rate limits. The default `0s` fetches every object with its own request.",
+			},
+			"read_batch_max_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      100,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Maximum number of saved objects fetched with a single `_bulk_get` request. The default is `100`.",
+			},
+			"write_batch_window": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "0s",
+				ValidateFunc: validateDuration,
+				Description:  "Saved objects created or updated within this duration of each other, e.g. `50ms`, are written with a single `_bulk_create` request per tenant, which speeds up the creation of fresh environments. Errors of single objects are reported by the resource of the object. The default `0s` writes every object with its own request.",
+			},
+			"write_batch_max_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      100,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Maximum number of saved objects written with a single `_bulk_create` request. The default is `100`.",
+			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"opensearch_saved_object":          resourceSavedObjects(),
-			"opensearch_default_index_pattern": resourceDefaultIndexPattern(),
+			"opensearch_saved_object":            resourceSavedObjects(),
+			"opensearch_default_index_pattern":   resourceDefaultIndexPattern(),
+			"opensearch_visualization":           resourceVisualization(),
+			"opensearch_vega_visualization":      resourceVegaVisualization(),
+			"opensearch_tsvb_visualization":      resourceTSVBVisualization(),
+			"opensearch_advanced_settings":       resourceAdvancedSettings(),
+			"opensearch_saved_objects_ownership": resourceSavedObjectsOwnership(),
+			"opensearch_dashboard_copy":          resourceDashboardCopy(),
 		},
 	}
 
@@ -80,11 +178,26 @@ type ProviderConfig struct {
 }
 
 type OpensearchDashboardsClient struct {
-	SavedObjects        *saved_objects.SavedObjectsProvider
-	DefaultIndexPattern *default_index_pattern.Provider
+	SavedObjects        saved_objects.Client
+	DefaultIndexPattern default_index_pattern.Client
+	AdvancedSettings    advanced_settings.Client
+
+	// Version is the version of OpenSearch Dashboards detected by the health check, nil if it was skipped
+	Version *status.Version
+	// ValidateReferences is one of off, warn and error
+	ValidateReferences string
+	// plannedCreates are the saved objects this provider instance plans to create, references to them are not
+	// validated
+	plannedCreates objectSet
+	// managedMarker and idNamespace are the ones applied by SavedObjects, nil if not configured
+	managedMarker *saved_objects.ManagedMarker
+	idNamespace   *saved_objects.IDNamespace
+
+	// the default index pattern and the advanced settings share one settings document, so writes to it are serialized
+	settingsLock sync.Mutex
 }
 
-func providerConfigure(_ context.Context, d *schema.ResourceData) (any, diag.Diagnostics) {
+func providerConfigure(ctx context.Context, d *schema.ResourceData) (any, diag.Diagnostics) {
 	var diags diag.Diagnostics
 
 	cfg := &ProviderConfig{
@@ -97,7 +210,16 @@ func providerConfigure(_ context.Context, d *schema.ResourceData) (any, diag.Dia
 		disableAuthentication = v.(bool)
 	}
 
-	signer, err := getRoundTripper(disableAuthentication)
+	var sensitivePaths []string
+	for _, path := range d.Get("sensitive_attribute_paths").([]any) {
+		sensitivePaths = append(sensitivePaths, path.(string))
+	}
+	logger := httplog.NewTransport(http.DefaultTransport, httplog.Options{
+		SensitivePaths: sensitivePaths,
+		LogBodies:      httplog.TraceFromEnv(),
+	})
+
+	signer, err := getRoundTripper(disableAuthentication, logger)
 	if err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Error,
@@ -108,6 +230,15 @@ func providerConfigure(_ context.Context, d *schema.ResourceData) (any, diag.Dia
 	}
 	cfg.RoundTripper = signer
 
+	if path := d.Get("har_file").(string); path != "" {
+		recorder, err := har.Open(path, "")
+		if err != nil {
+			return nil, diag.Errorf("could not open HAR file %s: %v", path, err)
+		}
+		// the recorder wraps the signer, so it records the requests before they are signed
+		cfg.RoundTripper = har.NewTransport(cfg.RoundTripper, recorder, httplog.NewRedactor(sensitivePaths))
+	}
+
 	var syncIndexPatternFields bool
 	if v, ok := d.GetOk("sync_index_pattern_fields"); ok {
 		syncIndexPatternFields = v.(bool)
@@ -115,20 +246,94 @@ func providerConfigure(_ context.Context, d *schema.ResourceData) (any, diag.Dia
 
 	// init providers
 	savedObjectsProvider := saved_objects.NewSavedObjectsProvider(cfg.BaseUrl, &http.Client{Transport: cfg.RoundTripper}, syncIndexPatternFields)
+	if v, ok := d.GetOk("managed_marker.0"); ok {
+		marker := v.(map[string]any)
+		savedObjectsProvider.ManagedMarker = &saved_objects.ManagedMarker{
+			TitleSuffix:       marker["title_suffix"].(string),
+			DescriptionSuffix: marker["description_suffix"].(string),
+		}
+	}
 	defaultIndexPatternProvider := default_index_pattern.NewProvider(cfg.BaseUrl, &http.Client{Transport: cfg.RoundTripper})
+	advancedSettingsProvider := advanced_settings.NewProvider(cfg.BaseUrl, &http.Client{Transport: cfg.RoundTripper})
 
 	// pass providers to the client
 	client := &OpensearchDashboardsClient{
 		SavedObjects:        savedObjectsProvider,
 		DefaultIndexPattern: defaultIndexPatternProvider,
+		AdvancedSettings:    advancedSettingsProvider,
+		ValidateReferences:  d.Get("validate_references").(string),
+		managedMarker:       savedObjectsProvider.ManagedMarker,
+	}
+
+	readBatchWindow, err := time.ParseDuration(d.Get("read_batch_window").(string))
+	if err != nil {
+		return nil, diag.Errorf("read_batch_window is not a valid duration: %v", err)
+	}
+	if readBatchWindow > 0 {
+		savedObjectsProvider.BatchReads(readBatchWindow, d.Get("read_batch_max_size").(int))
+	}
+	writeBatchWindow, err := time.ParseDuration(d.Get("write_batch_window").(string))
+	if err != nil {
+		return nil, diag.Errorf("write_batch_window is not a valid duration: %v", err)
+	}
+	if writeBatchWindow > 0 {
+		savedObjectsProvider.BatchWrites(writeBatchWindow, d.Get("write_batch_max_size").(int))
+	}
+
+	prefix, suffix := d.Get("object_id_prefix").(string), d.Get("object_id_suffix").(string)
+	if prefix != "" || suffix != "" {
+		savedObjectsProvider.IDNamespace = &saved_objects.IDNamespace{
+			Prefix: prefix,
+			Suffix: suffix,
+		}
+		client.idNamespace = savedObjectsProvider.IDNamespace
+	}
+
+	if !d.Get("skip_health_check").(bool) {
+		version, healthDiags := healthCheck(ctx, status.NewProvider(cfg.BaseUrl, &http.Client{Transport: cfg.RoundTripper}), cfg.BaseUrl)
+		diags = append(diags, healthDiags...)
+		if diags.HasError() {
+			return nil, diags
+		}
+		client.Version = version
+	}
+
+	return client, diags
+}
+
+// majorVersion returns the major version of OpenSearch Dashboards, 0 if it is unknown because the health check
+// was skipped.
+func (c *OpensearchDashboardsClient) majorVersion() int {
+	if c.Version == nil {
+		return 0
+	}
+
+	return c.Version.Major()
+}
+
+// checkManagedMarker rejects a title or description which ends with the managed marker already, once it is known.
+func (c *OpensearchDashboardsClient) checkManagedMarker(d *schema.ResourceDiff, objType string) error {
+	attributes := map[string]any{}
+	for _, key := range []string{"title", "description"} {
+		if d.NewValueKnown(key) {
+			attributes[key] = d.Get(key)
+		}
+	}
+
+	return c.managedMarker.Check(objType, attributes)
+}
+
+func validateDuration(v any, key string) ([]string, []error) {
+	if _, err := time.ParseDuration(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s is not a valid duration, e.g. 10ms: %w", key, err)}
 	}
 
-	return client, nil
+	return nil, nil
 }
 
-func getRoundTripper(disableAuthentication bool) (http.RoundTripper, error) {
+func getRoundTripper(disableAuthentication bool, next http.RoundTripper) (http.RoundTripper, error) {
 	if disableAuthentication {
-		return sigv4.RoundTripperFunc(http.DefaultTransport.RoundTrip), nil
+		return next, nil
 	}
 
 	sess, err := session.NewSession()
@@ -142,7 +347,7 @@ func getRoundTripper(disableAuthentication bool) (http.RoundTripper, error) {
 			Region:  *sess.Config.Region,
 		},
 		sess.Config.Credentials,
-		http.DefaultTransport)
+		next)
 	if err != nil {
 		return nil, fmt.Errorf("could not create sigv4 Http request signer: %w", err)
 	}
@@ -0,0 +1,57 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/fakeosd"
+)
+
+const testAccPathPrefix = "/_dashboards"
+
+// testAccProviderFactories serve the provider in-process to the Terraform CLI run by resource.Test.
+var testAccProviderFactories = map[string]func() (*schema.Provider, error){
+	"opensearch": func() (*schema.Provider, error) {
+		return Provider(), nil
+	},
+}
+
+// testAccServer starts an in-memory OpenSearch Dashboards of the profile for an acceptance test. It returns the
+// server and the provider block pointing to it, which is prepended to the configurations of the test steps. The
+// server uses the default path_prefix of the provider.
+func testAccServer(t *testing.T, profile fakeosd.Profile) (*fakeosd.Server, string) {
+	t.Helper()
+	s := fakeosd.New(fakeosd.Options{Profile: profile, BasePath: testAccPathPrefix})
+	t.Cleanup(s.Close)
+
+	return s, fmt.Sprintf(`
+provider "opensearch" {
+  base_url               = %q
+  disable_authentication = true
+}
+`, strings.TrimSuffix(s.URL, testAccPathPrefix))
+}
+
+func TestProvider(t *testing.T) {
+	if err := Provider().InternalValidate(); err != nil {
+		t.Fatal(err)
+	}
+}
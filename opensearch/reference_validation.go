@@ -0,0 +1,114 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+const (
+	referenceValidationOff   = "off"
+	referenceValidationWarn  = "warn"
+	referenceValidationError = "error"
+)
+
+// objectSet is a set of saved objects which can be used concurrently.
+type objectSet struct {
+	mu      sync.Mutex
+	objects map[saved_objects.ObjectKey]bool
+}
+
+func (s *objectSet) add(key saved_objects.ObjectKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.objects == nil {
+		s.objects = map[saved_objects.ObjectKey]bool{}
+	}
+	s.objects[key] = true
+}
+
+func (s *objectSet) contains(key saved_objects.ObjectKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.objects[key]
+}
+
+// planCreate records that the resource creates a saved object of the type, so references to it are not
+// validated. Terraform plans every resource again right before applying it, so this also happens during the apply.
+func (c *OpensearchDashboardsClient) planCreate(d *schema.ResourceDiff, objType string) {
+	if d.Id() != "" || !d.NewValueKnown("obj_id") {
+		return
+	}
+	c.plannedCreates.add(saved_objects.ObjectKey{Type: objType, ID: d.Get("obj_id").(string)})
+}
+
+// validateReferencesExist checks that the targets of all references of obj exist, if enabled by the provider
+// setting validate_references. Depending on the setting, missing targets are reported as warnings or errors.
+// Targets which are created in the same run are not reported.
+func (c *OpensearchDashboardsClient) validateReferencesExist(ctx context.Context, obj *saved_objects.SavedObjectOSD) diag.Diagnostics {
+	if c.ValidateReferences == referenceValidationOff || c.ValidateReferences == "" {
+		return nil
+	}
+
+	found, err := c.SavedObjects.FindMissingReferences(ctx, obj.Tenant, obj.References)
+	if err != nil {
+		return errorDiagnostics(err, "could not validate references of %s %q", obj.Type, obj.ID)
+	}
+	var missing []saved_objects.Reference
+	for _, ref := range found {
+		if !c.plannedCreates.contains(saved_objects.ObjectKey{Type: ref.Type, ID: ref.ID}) {
+			missing = append(missing, ref)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, 0, len(missing))
+	for _, ref := range missing {
+		if ref.Name != "" {
+			descriptions = append(descriptions, fmt.Sprintf("%s %q (%s)", ref.Type, ref.ID, ref.Name))
+		} else {
+			descriptions = append(descriptions, fmt.Sprintf("%s %q", ref.Type, ref.ID))
+		}
+	}
+
+	severity := diag.Warning
+	if c.ValidateReferences == referenceValidationError {
+		severity = diag.Error
+	}
+
+	summary := fmt.Sprintf("%s %q references objects which do not exist", obj.Type, obj.ID)
+	if obj.Tenant != "" {
+		summary += fmt.Sprintf(" in tenant %q", obj.Tenant)
+	}
+
+	return diag.Diagnostics{{
+		Severity: severity,
+		Summary:  summary,
+		Detail:   "The following references do not resolve: " + strings.Join(descriptions, ", "),
+	}}
+}
@@ -0,0 +1,91 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/fakeosd"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+func TestValidateReferencesExist(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		reference map[string]any
+		wantError string
+	}{
+		{
+			desc:      "must accept existing targets",
+			reference: map[string]any{"id": "pattern", "name": "kibanaSavedObjectMeta.searchSourceJSON.index", "type": "index-pattern"},
+		},
+		{
+			desc:      "must accept targets which are planned to be created",
+			reference: map[string]any{"id": "planned", "name": "search_0", "type": "search"},
+		},
+		{
+			desc:      "must reject missing targets",
+			reference: map[string]any{"id": "missing", "name": "search_0", "type": "search"},
+			wantError: "references objects which do not exist",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := fakeosd.New(fakeosd.Options{})
+			defer s.Close()
+			s.Put("", fakeosd.Object{Type: "index-pattern", ID: "pattern", Attributes: map[string]any{"title": "logs-*"}})
+
+			hc := &OpensearchDashboardsClient{
+				SavedObjects:       saved_objects.NewSavedObjectsProvider(s.URL, http.DefaultClient, false),
+				ValidateReferences: referenceValidationError,
+			}
+			planned := terraform.NewResourceConfigRaw(map[string]any{"obj_id": "planned", "type": "search", "attributes": `{"title":"Errors"}`})
+			if _, err := resourceSavedObjects().SimpleDiff(context.Background(), nil, planned, hc); err != nil {
+				t.Fatal(err)
+			}
+
+			d := schema.TestResourceDataRaw(t, resourceSavedObjects().Schema, map[string]any{
+				"obj_id":     "errors",
+				"type":       "visualization",
+				"attributes": `{"title":"Errors"}`,
+				"references": []any{tC.reference},
+			})
+			diags := hc.writeObject(context.Background(), d, &saved_objects.SavedObjectOSD{
+				Type: "visualization",
+				ID:   "errors",
+				SavedObjectPostPayload: saved_objects.SavedObjectPostPayload{
+					Attributes: map[string]any{"title": "Errors"},
+					References: expandReferences(d.Get("references").(*schema.Set)),
+				},
+			})
+			if tC.wantError == "" {
+				if diags.HasError() {
+					t.Errorf("expected no error but got %v", diags)
+				}
+				return
+			}
+			if !diags.HasError() || !strings.Contains(diags[0].Summary, tC.wantError) {
+				t.Errorf("expected error %q but got %v", tC.wantError, diags)
+			}
+		})
+	}
+}
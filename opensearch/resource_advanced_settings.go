@@ -0,0 +1,152 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+)
+
+func resourceAdvancedSettings() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Manages advanced settings (uiSettings) of OpenSearch Dashboards. Only the configured keys are managed, all other settings are left untouched.",
+		ReadContext:   advancedSettingsRead,
+		CreateContext: advancedSettingsWrite,
+		UpdateContext: advancedSettingsWrite,
+		DeleteContext: advancedSettingsDelete,
+		Schema: map[string]*schema.Schema{
+			"settings": {
+				Description:      "Map of setting keys, for example `dateFormat:tz` or `theme:darkMode`, to their JSON encoded values. Use `jsonencode` to set the values.",
+				Type:             schema.TypeMap,
+				Required:         true,
+				Elem:             &schema.Schema{Type: schema.TypeString},
+				ValidateDiagFunc: validateJSONMapValues,
+				DiffSuppressFunc: structure.SuppressJsonDiff,
+			},
+		},
+	}
+}
+
+func validateJSONMapValues(v any, path cty.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for key, value := range v.(map[string]any) {
+		if s, ok := value.(string); !ok || !json.Valid([]byte(s)) {
+			diags = append(diags, diag.Diagnostic{
+				Severity:      diag.Error,
+				Summary:       "value is not valid JSON",
+				Detail:        "The value of " + key + " must be JSON encoded, for example with jsonencode.",
+				AttributePath: path.IndexString(key),
+			})
+		}
+	}
+	return diags
+}
+
+func advancedSettingsRead(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	current, err := hc.AdvancedSettings.GetSettings(ctx)
+	if err != nil {
+		return errorDiagnostics(err, "could not read advanced settings")
+	}
+
+	// only the managed keys are reported, a key which was reset out of band is removed so that it shows up as drift
+	settings := map[string]any{}
+	for key := range d.Get("settings").(map[string]any) {
+		value, ok := current[key]
+		if !ok {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return diag.Errorf("could not encode value of setting %s: %v", key, err)
+		}
+		settings[key] = string(encoded)
+	}
+
+	if err := d.Set("settings", settings); err != nil {
+		return diag.Errorf("could not set settings after fetching from api: %v", err)
+	}
+
+	return nil
+}
+
+func advancedSettingsWrite(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	changes := map[string]any{}
+	oldSettings, newSettings := d.GetChange("settings")
+	for key := range oldSettings.(map[string]any) {
+		// keys which are no longer managed are reset to their default
+		changes[key] = nil
+	}
+	for key, value := range newSettings.(map[string]any) {
+		var decoded any
+		if err := json.Unmarshal([]byte(value.(string)), &decoded); err != nil {
+			return diag.Errorf("value of setting %s is not valid JSON: %v", key, err)
+		}
+		changes[key] = decoded
+	}
+
+	hc.settingsLock.Lock()
+	defer hc.settingsLock.Unlock()
+
+	if err := hc.AdvancedSettings.SetSettings(ctx, changes); err != nil {
+		tflog.Error(ctx, "could not set advanced settings", map[string]any{"error": err.Error()})
+
+		return errorDiagnostics(err, "could not set advanced settings")
+	}
+
+	d.SetId("advanced-settings")
+
+	return nil
+}
+
+func advancedSettingsDelete(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	changes := map[string]any{}
+	for key := range d.Get("settings").(map[string]any) {
+		changes[key] = nil
+	}
+
+	hc.settingsLock.Lock()
+	defer hc.settingsLock.Unlock()
+
+	if err := hc.AdvancedSettings.SetSettings(ctx, changes); err != nil {
+		tflog.Error(ctx, "could not reset advanced settings", map[string]any{"error": err.Error()})
+
+		return errorDiagnostics(err, "could not reset advanced settings")
+	}
+
+	return nil
+}
@@ -0,0 +1,299 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+func resourceDashboardCopy() *schema.Resource {
+	return &schema.Resource{
+		Description: "Copies a dashboard together with all visualizations and searches it references, e.g. to point a standard dashboard at the index pattern of a team. " +
+			"References between the copied objects are rewritten to the copies. When the source changes, the copies are updated on the next apply.",
+		ReadContext:   resourceDashboardCopyRead,
+		CreateContext: resourceDashboardCopyWrite,
+		UpdateContext: resourceDashboardCopyWrite,
+		DeleteContext: resourceDashboardCopyDelete,
+		CustomizeDiff: resourceDashboardCopyCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"source_dashboard_id": {
+				Description: "ID of the dashboard to copy.",
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+			},
+			"id_prefix": {
+				Description:  "Prefix prepended to the IDs of the source objects to derive the IDs of the copies. Objects which already exist at these IDs are not overwritten, the apply fails instead.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringIsNotEmpty,
+			},
+			"title_template": {
+				Description: "Title of the copies. `" + saved_objects.TitlePlaceholder + "` is replaced with the title of the source object, e.g. `" + saved_objects.TitlePlaceholder + " (team-a)`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     saved_objects.TitlePlaceholder,
+			},
+			"index_pattern_replacements": {
+				Description: "Map of IDs of index patterns referenced by the source objects to the IDs of the index patterns the copies reference instead. Index patterns which are not listed are shared with the source.",
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"copies": {
+				Description: "The copies created as `type/id`. Exactly these objects are deleted on destroy.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"in_sync": {
+				Description: "Whether the copies match the current source objects. Set to false on refresh if the source or the copies changed.",
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dashboardCopyOptions(d resourceGetter) saved_objects.CopyOptions {
+	replacements := map[string]string{}
+	for k, v := range d.Get("index_pattern_replacements").(map[string]any) {
+		replacements[k] = v.(string)
+	}
+
+	return saved_objects.CopyOptions{
+		IDPrefix:                 d.Get("id_prefix").(string),
+		TitleTemplate:            d.Get("title_template").(string),
+		IndexPatternReplacements: replacements,
+	}
+}
+
+// dashboardCopies collects the source objects and returns their copies, the copy of the dashboard first. It
+// returns nil if the source dashboard does not exist. Copies which would replace a source object are rejected.
+func dashboardCopies(ctx context.Context, hc *OpensearchDashboardsClient, d *schema.ResourceData) ([]saved_objects.SavedObjectOSD, diag.Diagnostics) {
+	source := d.Get("source_dashboard_id").(string)
+	sources, err := hc.SavedObjects.CollectObjects(ctx, saved_objects.ObjectKey{Type: "dashboard", ID: source})
+	if err != nil {
+		return nil, errorDiagnostics(err, "could not read source dashboard %q", source)
+	}
+	if sources == nil {
+		return nil, nil
+	}
+
+	isSource := make(map[string]bool, len(sources))
+	for _, obj := range sources {
+		isSource[formatObjectKey(obj)] = true
+	}
+	copies := saved_objects.CopyObjects(sources, dashboardCopyOptions(d))
+	for _, obj := range copies {
+		if isSource[formatObjectKey(obj)] {
+			return nil, diag.Errorf("the copy %s would overwrite a source object of dashboard %q, choose another id_prefix", formatObjectKey(obj), source)
+		}
+	}
+
+	return copies, nil
+}
+
+func resourceDashboardCopyCustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ any) error {
+	if d.Id() == "" {
+		return nil
+	}
+
+	// drift of the source or the copies is applied by writing all copies again
+	if !d.Get("in_sync").(bool) {
+		if err := d.SetNew("in_sync", true); err != nil {
+			return err
+		}
+		return d.SetNewComputed("copies")
+	}
+
+	return nil
+}
+
+func resourceDashboardCopyRead(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	copies, diagnostics := dashboardCopies(ctx, hc, d)
+	if diagnostics != nil {
+		return diagnostics
+	}
+	if copies == nil {
+		// the copies can still be destroyed, they are only synced again once the source exists
+		tflog.Warn(ctx, "source dashboard of dashboard copy does not exist", map[string]any{"source_dashboard_id": d.Get("source_dashboard_id"), "id": d.Id()})
+		return nil
+	}
+
+	inSync := true
+	existing := map[string]bool{}
+	for _, key := range d.Get("copies").([]any) {
+		existing[key.(string)] = true
+	}
+	for i := range copies {
+		expected := copies[i]
+		if !existing[formatObjectKey(expected)] {
+			inSync = false
+			continue
+		}
+
+		actual, err := hc.SavedObjects.GetObject(ctx, &expected)
+		if err != nil && !errors.Is(err, apierror.ErrNotFound) {
+			return errorDiagnostics(err, "could not read copy %s", formatObjectKey(expected))
+		}
+		if actual == nil {
+			// deleted copies, including the copy of the dashboard, are written again by the next apply
+			inSync = false
+			continue
+		}
+
+		expectedTF, err := expected.ToTF()
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		inSync = inSync && sameObject(expectedTF, actual)
+	}
+	inSync = inSync && len(existing) == len(copies)
+
+	if err := d.Set("in_sync", inSync); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceDashboardCopyWrite(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	copies, diagnostics := dashboardCopies(ctx, hc, d)
+	if diagnostics != nil {
+		return diagnostics
+	}
+	if copies == nil {
+		return diag.Errorf("source dashboard %s does not exist", d.Get("source_dashboard_id"))
+	}
+
+	previous, _ := d.GetChange("copies")
+	owned := map[string]bool{}
+	for _, key := range previous.([]any) {
+		owned[key.(string)] = true
+	}
+
+	// objects at the IDs of new copies were not created by this resource, so they are neither overwritten nor
+	// deleted on destroy
+	for i := range copies {
+		key := formatObjectKey(copies[i])
+		if owned[key] {
+			continue
+		}
+		existing, err := hc.SavedObjects.GetObject(ctx, &copies[i])
+		if err != nil && !errors.Is(err, apierror.ErrNotFound) {
+			return errorDiagnostics(err, "could not check copy %s", key)
+		}
+		if existing != nil {
+			return diag.Errorf("%s already exists but was not created by this dashboard copy, delete it or choose another id_prefix", key)
+		}
+	}
+
+	// the referenced objects are saved before the objects referencing them
+	keys := make([]string, len(copies))
+	for i := len(copies) - 1; i >= 0; i-- {
+		if err := hc.SavedObjects.SaveObject(ctx, &copies[i]); err != nil {
+			// the copies saved so far are recorded, so they are deleted on destroy instead of failing the next apply
+			written := keys[i+1:]
+			for _, key := range previous.([]any) {
+				if !slices.Contains(written, key.(string)) {
+					written = append(written, key.(string))
+				}
+			}
+			d.SetId(copies[0].ID)
+			if err := d.Set("copies", written); err != nil {
+				return diag.FromErr(err)
+			}
+			return errorDiagnostics(err, "could not save copy %s", formatObjectKey(copies[i]))
+		}
+		keys[i] = formatObjectKey(copies[i])
+	}
+	d.SetId(copies[0].ID)
+
+	// copies of objects which were removed from the source are deleted
+	current := map[string]bool{}
+	for _, key := range keys {
+		current[key] = true
+	}
+	for _, key := range previous.([]any) {
+		if !current[key.(string)] {
+			if diagnostics := deleteDashboardCopy(ctx, hc, key.(string)); diagnostics != nil {
+				return diagnostics
+			}
+		}
+	}
+
+	if err := d.Set("copies", keys); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("in_sync", true); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceDashboardCopyDelete(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	for _, key := range d.Get("copies").([]any) {
+		if diagnostics := deleteDashboardCopy(ctx, hc, key.(string)); diagnostics != nil {
+			return diagnostics
+		}
+	}
+
+	return nil
+}
+
+func deleteDashboardCopy(ctx context.Context, hc *OpensearchDashboardsClient, key string) diag.Diagnostics {
+	objType, id, ok := strings.Cut(key, "/")
+	if !ok {
+		return diag.Errorf("invalid copy %q, expected type/id", key)
+	}
+
+	if err := hc.SavedObjects.DeleteObject(ctx, &saved_objects.SavedObjectOSD{Type: objType, ID: id}); err != nil {
+		tflog.Error(ctx, "could not delete copy", map[string]any{"copy": key, "error": err.Error()})
+
+		return errorDiagnostics(err, "could not delete copy %s", key)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/fakeosd"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+func TestDashboardCopyWrite(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		idPrefix string
+		existing []fakeosd.Object
+		// referenced marks the existing objects as referenced by the source dashboard
+		referenced bool
+		wantError  string
+		wantTitle  string
+	}{
+		{
+			desc:      "must create the copies",
+			idPrefix:  "team-a-",
+			wantTitle: "Errors",
+		},
+		{
+			desc:      "must not overwrite existing objects",
+			idPrefix:  "team-a-",
+			existing:  []fakeosd.Object{{Type: "visualization", ID: "team-a-v", Attributes: map[string]any{"title": "Created by hand"}}},
+			wantError: "visualization/team-a-v already exists",
+		},
+		{
+			desc:       "must not overwrite the source",
+			idPrefix:   "a",
+			existing:   []fakeosd.Object{{Type: "visualization", ID: "av", Attributes: map[string]any{"title": "Source"}}},
+			referenced: true,
+			wantError:  "would overwrite a source object",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := fakeosd.New(fakeosd.Options{})
+			defer s.Close()
+			references := []fakeosd.Reference{{Name: "panel_0", Type: "visualization", ID: "v"}}
+			for _, obj := range tC.existing {
+				s.Put("", obj)
+				if tC.referenced {
+					references = append(references, fakeosd.Reference{Name: "panel_" + obj.ID, Type: obj.Type, ID: obj.ID})
+				}
+			}
+			s.Put("", fakeosd.Object{Type: "visualization", ID: "v", Attributes: map[string]any{"title": "Errors"}})
+			s.Put("", fakeosd.Object{Type: "dashboard", ID: "d", Attributes: map[string]any{"title": "Overview"}, References: references})
+
+			hc := &OpensearchDashboardsClient{SavedObjects: saved_objects.NewSavedObjectsProvider(s.URL, http.DefaultClient, false)}
+			d := schema.TestResourceDataRaw(t, resourceDashboardCopy().Schema, map[string]any{
+				"source_dashboard_id": "d",
+				"id_prefix":           tC.idPrefix,
+			})
+
+			diags := resourceDashboardCopyWrite(context.Background(), d, hc)
+			if tC.wantError != "" {
+				if !diags.HasError() || !strings.Contains(diags[0].Summary, tC.wantError) {
+					t.Fatalf("expected error %q but got %v", tC.wantError, diags)
+				}
+				for _, obj := range tC.existing {
+					if got, _ := s.Get("", obj.Type, obj.ID); got.Attributes["title"] != obj.Attributes["title"] {
+						t.Errorf("expected %s/%s not to be overwritten but got %v", obj.Type, obj.ID, got.Attributes)
+					}
+				}
+				return
+			}
+			if diags.HasError() {
+				t.Fatal(diags)
+			}
+			copied, ok := s.Get("", "visualization", tC.idPrefix+"v")
+			if !ok || copied.Attributes["title"] != tC.wantTitle {
+				t.Errorf("expected the visualization to be copied but got %v", copied)
+			}
+			if copies := d.Get("copies").([]any); len(copies) != 2 {
+				t.Errorf("expected the dashboard and the visualization to be copied but got %v", copies)
+			}
+		})
+	}
+}
+
+func TestDashboardCopyDeletedOutside(t *testing.T) {
+	s := fakeosd.New(fakeosd.Options{})
+	defer s.Close()
+	s.Put("", fakeosd.Object{Type: "visualization", ID: "v", Attributes: map[string]any{"title": "Errors"}})
+	s.Put("", fakeosd.Object{Type: "dashboard", ID: "d", Attributes: map[string]any{"title": "Overview"},
+		References: []fakeosd.Reference{{Name: "panel_0", Type: "visualization", ID: "v"}}})
+
+	hc := &OpensearchDashboardsClient{SavedObjects: saved_objects.NewSavedObjectsProvider(s.URL, http.DefaultClient, false)}
+	r := resourceDashboardCopy()
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]any{
+		"source_dashboard_id": "d",
+		"id_prefix":           "team-a-",
+	})
+	if diags := resourceDashboardCopyWrite(context.Background(), d, hc); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	s.Delete("", "dashboard", "team-a-d")
+	d = r.Data(d.State())
+	if diags := resourceDashboardCopyRead(context.Background(), d, hc); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if d.Id() != "team-a-d" || d.Get("in_sync").(bool) {
+		t.Fatalf("expected the resource to be kept out of sync but got id %q, in_sync %v", d.Id(), d.Get("in_sync"))
+	}
+
+	if diags := resourceDashboardCopyWrite(context.Background(), d, hc); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if _, ok := s.Get("", "dashboard", "team-a-d"); !ok {
+		t.Error("expected the copy of the dashboard to be written again")
+	}
+}
+
+func TestDashboardCopyIDPrefix(t *testing.T) {
+	if _, errs := resourceDashboardCopy().Schema["id_prefix"].ValidateFunc("", "id_prefix"); len(errs) == 0 {
+		t.Error("expected an empty id_prefix to be rejected")
+	}
+}
@@ -2,29 +2,89 @@ package opensearch
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/rs/zerolog/log"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+const (
+	defaultIndexPatternResourceId = "default-pattern"
+
+	onDestroyUnset           = "unset"
+	onDestroyRestorePrevious = "restore_previous"
+	onDestroyKeep            = "keep"
 )
 
 func resourceDefaultIndexPattern() *schema.Resource {
 	return &schema.Resource{
-		Description:   "The default index pattern defines the default index pattern.",
+		Description:   "The default index pattern defines the default index pattern. The index pattern has to exist, this is checked in the plan already unless its ID is only known after apply or `skip_health_check` is set.",
 		ReadContext:   defaultIndexPatternRead,
-		CreateContext: defaultIndexPatternWrite,
+		CreateContext: defaultIndexPatternCreate,
 		UpdateContext: defaultIndexPatternWrite,
 		DeleteContext: defaultIndexPatternDelete,
+		CustomizeDiff: defaultIndexPatternCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			StateContext: defaultIndexPatternImport,
+		},
 		Schema: map[string]*schema.Schema{
 			"index_pattern_id": {
 				Description: "The unique identifier of the index pattern.",
 				Type:        schema.TypeString,
 				Required:    true,
 			},
+			"external": {
+				Description: "Marks the index pattern as outside of the `object_id_prefix` and `object_id_suffix` of the provider, e.g. a shared index pattern, so its ID is not namespaced. Without namespace it has no effect.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+			},
+			"on_destroy": {
+				Description:  "What happens to the default index pattern when this resource is destroyed. `unset` removes the default index pattern, `restore_previous` restores the default index pattern that was set before this resource was created (if that index pattern still exists) and `keep` leaves the default index pattern as it is.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      onDestroyUnset,
+				ValidateFunc: validation.StringInSlice([]string{onDestroyUnset, onDestroyRestorePrevious, onDestroyKeep}, false),
+			},
+			"previous_index_pattern_id": {
+				Description: "The default index pattern that was set before this resource was created. Empty if there was none.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
 		},
 	}
 }
 
+func defaultIndexPatternImport(ctx context.Context, d *schema.ResourceData, m any) ([]*schema.ResourceData, error) {
+	if d.Id() != defaultIndexPatternResourceId {
+		return nil, fmt.Errorf("the default index pattern can only be imported with the ID %q, got %q", defaultIndexPatternResourceId, d.Id())
+	}
+
+	// defaults are not set on import, the first plan would show them as change otherwise
+	if err := d.Set("on_destroy", onDestroyUnset); err != nil {
+		return nil, err
+	}
+
+	external := false
+	if hc, ok := m.(*OpensearchDashboardsClient); ok && hc.idNamespace != nil {
+		resp, err := hc.DefaultIndexPattern.GetDefaultIndexPattern(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not read default index pattern: %w", err)
+		}
+		external = resp != nil && resp.IndexPatternId != nil && !hc.idNamespace.Contains(*resp.IndexPatternId)
+	}
+	if err := d.Set("external", external); err != nil {
+		return nil, err
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func defaultIndexPatternDelete(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
 	hc, isAssertedType := m.(*OpensearchDashboardsClient)
 
@@ -32,14 +92,38 @@ func defaultIndexPatternDelete(ctx context.Context, d *schema.ResourceData, m an
 		return diag.Errorf("unexpected type provided as client: %T", m)
 	}
 
-	diagnostics := hc.DefaultIndexPattern.SetDefaultIndexPattern(ctx, nil)
-	if diagnostics != nil {
-		log.Error().Msgf("could not remove default index pattern. Terraform diagnostics: %v", diagnostics)
-
-		return diagnostics
+	var restore *string
+	switch d.Get("on_destroy").(string) {
+	case onDestroyKeep:
+		return nil
+	case onDestroyRestorePrevious:
+		previous := d.Get("previous_index_pattern_id").(string)
+		if previous == "" {
+			break
+		}
+		// the previous ID was read from the settings as is, so it must not be namespaced again
+		objs, err := hc.SavedObjects.BulkGetObjects(ctx, "", []saved_objects.ObjectKey{{Type: "index-pattern", ID: previous}})
+		if err != nil {
+			return errorDiagnostics(err, "could not get previous default index pattern %s", previous)
+		}
+		if err := objs[0].Error; err != nil && err.StatusCode != http.StatusNotFound {
+			return diag.Errorf("could not get previous default index pattern %s: %d %s", previous, err.StatusCode, err.Message)
+		}
+		if objs[0].Error != nil {
+			tflog.Warn(ctx, "previous default index pattern does not exist anymore, removing the default index pattern instead", map[string]any{"previous_index_pattern_id": previous})
+			break
+		}
+		restore = &previous
 	}
 
-	d.SetId("default-pattern")
+	hc.settingsLock.Lock()
+	defer hc.settingsLock.Unlock()
+
+	if err := hc.DefaultIndexPattern.SetDefaultIndexPattern(ctx, restore); err != nil {
+		tflog.Error(ctx, "could not remove default index pattern", map[string]any{"error": err.Error()})
+
+		return errorDiagnostics(err, "could not remove default index pattern")
+	}
 
 	return nil
 }
@@ -50,20 +134,54 @@ func defaultIndexPatternRead(ctx context.Context, d *schema.ResourceData, m any)
 		return diag.Errorf("unexpected type provided as client: %T", m)
 	}
 
-	resp, diagnostics := hc.DefaultIndexPattern.GetDefaultIndexPattern(ctx)
-	if diagnostics != nil {
-		return diagnostics
+	resp, err := hc.DefaultIndexPattern.GetDefaultIndexPattern(ctx)
+	if err != nil {
+		return errorDiagnostics(err, "could not read default index pattern")
+	}
+
+	if resp == nil || resp.IndexPatternId == nil {
+		// no default index pattern is set (anymore), signals the resource must be (re)created
+		d.SetId("")
+		return nil
 	}
-	err := d.Set("index_pattern_id", resp.IndexPatternId)
+
+	patternId := *resp.IndexPatternId
+	if !d.Get("external").(bool) {
+		patternId = hc.idNamespace.Strip(patternId)
+	}
+	err = d.Set("index_pattern_id", patternId)
 	if err != nil {
 		return diag.Errorf("could not read index_pattern_id after fetching from api: %v+", err)
 	}
 
-	d.SetId("default-pattern")
+	d.SetId(defaultIndexPatternResourceId)
 
 	return nil
 }
 
+func defaultIndexPatternCreate(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	// remember the current default so it can be restored on destroy
+	resp, err := hc.DefaultIndexPattern.GetDefaultIndexPattern(ctx)
+	if err != nil {
+		return errorDiagnostics(err, "could not read default index pattern")
+	}
+	previous := ""
+	if resp != nil && resp.IndexPatternId != nil {
+		previous = *resp.IndexPatternId
+	}
+	if err := d.Set("previous_index_pattern_id", previous); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return defaultIndexPatternWrite(ctx, d, m)
+}
+
 func defaultIndexPatternWrite(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
 	hc, isAssertedType := m.(*OpensearchDashboardsClient)
 
@@ -72,14 +190,60 @@ func defaultIndexPatternWrite(ctx context.Context, d *schema.ResourceData, m any
 	}
 
 	patternId := d.Get("index_pattern_id").(string)
-	diagnostics := hc.DefaultIndexPattern.SetDefaultIndexPattern(ctx, &patternId)
-	if diagnostics != nil {
-		log.Error().Msgf("could not set default index pattern. Terraform diagnostics: %v", diagnostics)
 
+	patternId, diagnostics := resolveIndexPattern(ctx, hc, patternId, d.Get("external").(bool))
+	if diagnostics != nil {
 		return diagnostics
 	}
 
-	d.SetId("default-pattern")
+	hc.settingsLock.Lock()
+	defer hc.settingsLock.Unlock()
+
+	if err := hc.DefaultIndexPattern.SetDefaultIndexPattern(ctx, &patternId); err != nil {
+		tflog.Error(ctx, "could not set default index pattern", map[string]any{"error": err.Error()})
+
+		return errorDiagnostics(err, "could not set default index pattern")
+	}
+
+	d.SetId(defaultIndexPatternResourceId)
+
+	return nil
+}
+
+// defaultIndexPatternCustomizeDiff reports a missing index pattern in the plan already. Index patterns which are
+// created in the same run are unknown until apply, they are checked by resolveIndexPattern then. So are all index
+// patterns if the health check is skipped, since the provider may be configured without connection.
+func defaultIndexPatternCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m any) error {
+	hc, ok := m.(*OpensearchDashboardsClient)
+	if !ok || hc.Version == nil || !d.NewValueKnown("index_pattern_id") || !d.HasChanges("index_pattern_id", "external") {
+		return nil
+	}
+
+	if _, diags := resolveIndexPattern(ctx, hc, d.Get("index_pattern_id").(string), d.Get("external").(bool)); diags.HasError() {
+		return fmt.Errorf("%s: %s", diags[0].Summary, diags[0].Detail)
+	}
 
 	return nil
 }
+
+// resolveIndexPattern returns the ID of the index pattern to use as default, namespaced like a reference unless it
+// is external. It prevents pointing the default index pattern to an index pattern which does not exist, which
+// OpenSearch Dashboards would accept without complaining.
+func resolveIndexPattern(ctx context.Context, hc *OpensearchDashboardsClient, patternId string, external bool) (string, diag.Diagnostics) {
+	ref := saved_objects.Reference{Type: "index-pattern", ID: patternId, External: external}
+	missing, err := hc.SavedObjects.FindMissingReferences(ctx, "", []saved_objects.Reference{ref})
+	if err != nil {
+		return "", errorDiagnostics(err, "could not look up index pattern %q", patternId)
+	}
+
+	if len(missing) > 0 {
+		return "", diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "index pattern does not exist",
+			Detail:        fmt.Sprintf("There is no index-pattern saved object with the ID %q. Create the index pattern first, for example with an opensearch_saved_object resource of type index-pattern, and reference its obj_id.", patternId),
+			AttributePath: cty.GetAttrPath("index_pattern_id"),
+		}}
+	}
+
+	return hc.idNamespace.ApplyReference(ref).ID, nil
+}
@@ -0,0 +1,238 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/default_index_pattern"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/fakeosd"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/status"
+)
+
+func testAccDefaultIndexPatternConfig(provider, pattern string) string {
+	return provider + fmt.Sprintf(`
+resource "opensearch_saved_object" "logs" {
+  obj_id     = "acc-logs"
+  type       = "index-pattern"
+  attributes = jsonencode({ title = "logs-*" })
+}
+
+resource "opensearch_saved_object" "metrics" {
+  obj_id     = "acc-metrics"
+  type       = "index-pattern"
+  attributes = jsonencode({ title = "metrics-*" })
+}
+
+resource "opensearch_default_index_pattern" "default" {
+  index_pattern_id = opensearch_saved_object.%s.obj_id
+}
+`, pattern)
+}
+
+// testAccCheckDefaultIndex checks the default index pattern on the server, nil if none is set.
+func testAccCheckDefaultIndex(s *fakeosd.Server, want any) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if got := s.Settings("")["defaultIndex"]; got != want {
+			return fmt.Errorf("expected default index pattern %v but got %v", want, got)
+		}
+
+		return nil
+	}
+}
+
+func TestAccDefaultIndexPattern(t *testing.T) {
+	s, provider := testAccServer(t, fakeosd.Profile2)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckDefaultIndex(s, nil),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDefaultIndexPatternConfig(provider, "logs"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("opensearch_default_index_pattern.default", "index_pattern_id", "acc-logs"),
+					resource.TestCheckResourceAttr("opensearch_default_index_pattern.default", "previous_index_pattern_id", ""),
+					testAccCheckDefaultIndex(s, "acc-logs"),
+				),
+			},
+			{
+				Config: testAccDefaultIndexPatternConfig(provider, "metrics"),
+				Check:  testAccCheckDefaultIndex(s, "acc-metrics"),
+			},
+			{
+				ResourceName:      "opensearch_default_index_pattern.default",
+				ImportState:       true,
+				ImportStateId:     defaultIndexPatternResourceId,
+				ImportStateVerify: true,
+				// the previous default is not stored on the server
+				ImportStateVerifyIgnore: []string{"previous_index_pattern_id"},
+			},
+			{
+				// a default changed in the UI is detected as drift
+				PreConfig: func() {
+					s.SetSetting("", "defaultIndex", "acc-logs")
+				},
+				Config:             testAccDefaultIndexPatternConfig(provider, "metrics"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccDefaultIndexPatternConfig(provider, "metrics"),
+				Check:  testAccCheckDefaultIndex(s, "acc-metrics"),
+			},
+			{
+				// a default removed in the UI is set again
+				PreConfig: func() {
+					s.SetSetting("", "defaultIndex", nil)
+				},
+				Config:             testAccDefaultIndexPatternConfig(provider, "metrics"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccDefaultIndexPatternConfig(provider, "metrics"),
+				Check:  testAccCheckDefaultIndex(s, "acc-metrics"),
+			},
+		},
+	})
+}
+
+func TestDefaultIndexPatternCustomizeDiff(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		indexPatternId  string
+		external        bool
+		namespace       *saved_objects.IDNamespace
+		skipHealthCheck bool
+		wantError       string
+	}{
+		{
+			desc:           "must accept existing index patterns",
+			indexPatternId: "logs",
+		},
+		{
+			desc:           "must reject missing index patterns",
+			indexPatternId: "metrics",
+			wantError:      "index pattern does not exist",
+		},
+		{
+			desc:           "must look up index patterns in the namespace",
+			indexPatternId: "logs",
+			namespace:      &saved_objects.IDNamespace{Prefix: "staging-"},
+			wantError:      "index pattern does not exist",
+		},
+		{
+			desc:           "must look up external index patterns outside of the namespace",
+			indexPatternId: "logs",
+			external:       true,
+			namespace:      &saved_objects.IDNamespace{Prefix: "staging-"},
+		},
+		{
+			desc:           "must skip unknown index patterns",
+			indexPatternId: unknownValue,
+		},
+		{
+			desc:            "must skip the check without health check",
+			indexPatternId:  "metrics",
+			skipHealthCheck: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := fakeosd.New(fakeosd.Options{})
+			defer s.Close()
+			s.Put("", fakeosd.Object{Type: "index-pattern", ID: "logs", Attributes: map[string]any{"title": "logs-*"}})
+
+			savedObjects := saved_objects.NewSavedObjectsProvider(s.URL, http.DefaultClient, false)
+			savedObjects.IDNamespace = tC.namespace
+			hc := &OpensearchDashboardsClient{SavedObjects: savedObjects, idNamespace: tC.namespace}
+			if !tC.skipHealthCheck {
+				hc.Version = &status.Version{Number: fakeosd.Profile2.Version}
+			}
+			config := terraform.NewResourceConfigRaw(map[string]any{"index_pattern_id": tC.indexPatternId, "external": tC.external})
+
+			_, err := resourceDefaultIndexPattern().SimpleDiff(context.Background(), nil, config, hc)
+			if tC.wantError == "" {
+				if err != nil {
+					t.Errorf("expected no error but got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tC.wantError) {
+				t.Errorf("expected error %q but got %v", tC.wantError, err)
+			}
+		})
+	}
+}
+
+func TestDefaultIndexPatternImport(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		defaultIndex string
+		wantExternal bool
+	}{
+		{
+			desc:         "must import index patterns in the namespace",
+			defaultIndex: "staging-logs",
+		},
+		{
+			desc:         "must import index patterns outside of the namespace as external",
+			defaultIndex: "shared",
+			wantExternal: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := fakeosd.New(fakeosd.Options{})
+			defer s.Close()
+			s.SetSetting("", "defaultIndex", tC.defaultIndex)
+
+			namespace := &saved_objects.IDNamespace{Prefix: "staging-"}
+			hc := &OpensearchDashboardsClient{
+				DefaultIndexPattern: default_index_pattern.NewProvider(s.URL, http.DefaultClient),
+				idNamespace:         namespace,
+			}
+			r := resourceDefaultIndexPattern()
+			d := r.Data(nil)
+			d.SetId(defaultIndexPatternResourceId)
+
+			imported, err := r.Importer.StateContext(context.Background(), d, hc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diags := r.ReadContext(context.Background(), imported[0], hc); diags.HasError() {
+				t.Fatal(diags)
+			}
+
+			got := imported[0]
+			if got.Get("on_destroy") != onDestroyUnset || got.Get("external") != tC.wantExternal {
+				t.Errorf("expected on_destroy %q and external %v but got %v and %v", onDestroyUnset, tC.wantExternal, got.Get("on_destroy"), got.Get("external"))
+			}
+			if want := namespace.Strip(tC.defaultIndex); got.Get("index_pattern_id") != want {
+				t.Errorf("expected index_pattern_id %q but got %q", want, got.Get("index_pattern_id"))
+			}
+		})
+	}
+}
@@ -19,20 +19,25 @@ limitations under the License.
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
-	"github.com/rs/zerolog/log"
 )
 
 func resourceSavedObjects() *schema.Resource {
-	return &schema.Resource{
-		Description:   "Manages saved objects in OpenSearch Dashboards.",
+	return addTenantsSchema(addDeletionProtectionSchema(&schema.Resource{
+		Description:   "Manages saved objects in OpenSearch Dashboards. Existing objects can be imported with the ID `<type>/<obj_id>`. Imported objects have `auto_references` disabled, so the IDs are read into `references`. If the configuration enables `auto_references` and writes IDs inline, the first plan after the import shows an update of `attributes` and `references`, which is resolved by applying it.",
 		ReadContext:   resourceSavedObjectRead,
 		CreateContext: resourceSavedObjectWrite,
 		UpdateContext: resourceSavedObjectWrite,
 		DeleteContext: resourceSavedObjectsDelete,
+		CustomizeDiff: resourceSavedObjectsCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceSavedObjectImport,
+		},
 		Schema: map[string]*schema.Schema{
 			"obj_id": {
 				Description: "ID of the saved object.",
@@ -48,12 +53,19 @@ func resourceSavedObjects() *schema.Resource {
 			},
 			"attributes": {
 				// This will contain stringified JSON. We'll just send the content to the OpenSearch Dashboards API
-				Description: "Attributes of the saved object.",
-				Type:        schema.TypeString,
-				Required:    true,
+				Description:      "Attributes of the saved object.",
+				Type:             schema.TypeString,
+				Required:         true,
+				DiffSuppressFunc: suppressEquivalentAttributes,
+			},
+			"auto_references": {
+				Description: "If set, IDs written inline into the attributes are moved to references with canonical names before the object is sent, like OpenSearch Dashboards does itself. Supported are `index` and `filter[*].meta.index` in `kibanaSavedObjectMeta.searchSourceJSON`, `id` and `type` of the entries of `panelsJSON`, `savedSearchId` and `indexPattern` of input controls in `visState`. When reading the object, the IDs are written back into the attributes, so `references` only needs to contain additional references.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
 			},
 			"references": {
-				Description: "References of the saved object.",
+				Description: "References of the saved object. At plan time it is checked that every reference name used in the attributes (e.g. `indexRefName` or `panelRefName`) has a reference with this name. Named references which are not used are reported as a warning when the object is written.",
 				Type:        schema.TypeSet,
 				Optional:    true,
 				Elem: &schema.Resource{
@@ -70,11 +82,44 @@ func resourceSavedObjects() *schema.Resource {
 							Type:     schema.TypeString,
 							Required: true,
 						},
+						"external": {
+							Description: "Marks a reference to an object outside of the `object_id_prefix` and `object_id_suffix` of the provider, e.g. a shared index pattern, so its ID is not namespaced. Without namespace it has no effect.",
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+						},
 					},
 				},
 			},
 		},
+	}))
+}
+
+// resourceSavedObjectImport splits the import ID into type and obj_id, the attributes are set by the read.
+func resourceSavedObjectImport(_ context.Context, d *schema.ResourceData, _ any) ([]*schema.ResourceData, error) {
+	objType, objID, ok := strings.Cut(d.Id(), "/")
+	if !ok || objType == "" || objID == "" {
+		return nil, fmt.Errorf("saved objects can only be imported with an ID of the form <type>/<obj_id>, got %q", d.Id())
+	}
+
+	values := map[string]any{
+		"obj_id":     objID,
+		"type":       objType,
+		"attributes": "{}",
+		// the defaults are not set on import, without them the first plan would show a change. References are read
+		// as they are stored, the configuration decides whether they are written inline with auto_references.
+		"auto_references":     false,
+		"deletion_protection": false,
+		"force_destroy":       false,
+	}
+	for key, value := range values {
+		if err := d.Set(key, value); err != nil {
+			return nil, fmt.Errorf("could not set %s: %w", key, err)
+		}
 	}
+	d.SetId(objID)
+
+	return []*schema.ResourceData{d}, nil
 }
 
 func resourceSavedObjectsToRequest(resource *schema.ResourceData) (*saved_objects.SavedObjectOSD, diag.Diagnostics) {
@@ -108,23 +153,104 @@ func resourceSavedObjectsToRequest(resource *schema.ResourceData) (*saved_object
 	result.Attributes = attrMap
 
 	if refsAny, ok := resource.GetOk("references"); ok {
-		var refs []saved_objects.Reference
-
-		for _, rAny := range refsAny.(*schema.Set).List() {
-			rMap := rAny.(map[string]any)
+		result.References = expandReferences(refsAny.(*schema.Set))
+	}
 
-			refs = append(refs, saved_objects.Reference{
-				ID:   rMap["id"].(string),
-				Name: rMap["name"].(string),
-				Type: rMap["type"].(string),
-			})
+	if resource.Get("auto_references").(bool) {
+		attrMap, refs, err := saved_objects.ExtractReferences(result.Attributes)
+		if err != nil {
+			return nil, diag.Errorf("could not extract references from attributes: %v", err)
 		}
-		result.References = refs
+		result.Attributes = attrMap
+		result.References = append(result.References, refs...)
 	}
 
 	return result, nil
 }
 
+// suppressEquivalentAttributes ignores differences in formatting and key order, also of stringified JSON
+// nested in the attributes. Those occur for example when auto_references re-encodes searchSourceJSON.
+func suppressEquivalentAttributes(_, oldValue, newValue string, _ *schema.ResourceData) bool {
+	return saved_objects.EquivalentAttributes(oldValue, newValue)
+}
+
+func expandReferences(refsSet *schema.Set) []saved_objects.Reference {
+	var refs []saved_objects.Reference
+
+	for _, rAny := range refsSet.List() {
+		rMap := rAny.(map[string]any)
+
+		refs = append(refs, saved_objects.Reference{
+			ID:       rMap["id"].(string),
+			Name:     rMap["name"].(string),
+			Type:     rMap["type"].(string),
+			External: rMap["external"].(bool),
+		})
+	}
+
+	return refs
+}
+
+// typesSince are the saved object types which only exist since a major version of OpenSearch Dashboards.
+var typesSince = map[string]int{
+	"visualization-visbuilder": 2,
+	"augment-vis":              2,
+}
+
+// resourceSavedObjectsCustomizeDiff verifies at plan time that the reference names used in the attributes
+// and the references match, which OpenSearch Dashboards only reports when the object is opened. Types which
+// the detected version of OpenSearch Dashboards does not support are rejected as well.
+func resourceSavedObjectsCustomizeDiff(_ context.Context, d *schema.ResourceDiff, m any) error {
+	if hc, ok := m.(*OpensearchDashboardsClient); ok {
+		objType := d.Get("type").(string)
+		if since, ok := typesSince[objType]; ok && hc.majorVersion() > 0 && hc.majorVersion() < since {
+			return fmt.Errorf("saved objects of type %s are not supported by OpenSearch Dashboards %s, they require version %d.x", objType, hc.Version, since)
+		}
+		if d.NewValueKnown("type") {
+			hc.planCreate(d, objType)
+		}
+	}
+
+	if !d.NewValueKnown("attributes") {
+		return nil
+	}
+
+	attrMap := make(map[string]any)
+	err := json.Unmarshal([]byte(d.Get("attributes").(string)), &attrMap)
+	if err != nil {
+		return fmt.Errorf("attributes is not valid json: %w", err)
+	}
+
+	if hc, ok := m.(*OpensearchDashboardsClient); ok {
+		if err := hc.managedMarker.Check(d.Get("type").(string), attrMap); err != nil {
+			return fmt.Errorf("attributes: %w", err)
+		}
+	}
+
+	// the references are compared with the attributes once both are known, e.g. references of a dynamic block are
+	// unknown until its for_each is known. Sets are never reported as unknown, only their count.
+	if !d.NewValueKnown("references.#") {
+		return nil
+	}
+
+	refs := expandReferences(d.Get("references").(*schema.Set))
+
+	if d.Get("auto_references").(bool) {
+		var extracted []saved_objects.Reference
+		attrMap, extracted, err = saved_objects.ExtractReferences(attrMap)
+		if err != nil {
+			return fmt.Errorf("could not extract references from attributes: %w", err)
+		}
+		refs = append(refs, extracted...)
+	}
+
+	if err := saved_objects.ValidateReferences(attrMap, refs); err != nil {
+		return fmt.Errorf("references of %s %q do not match its attributes: %w", d.Get("type"), d.Get("obj_id"), err)
+	}
+
+	return nil
+}
+
 func resourceSavedObjectsDelete(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
 	hc, isAssertedType := m.(*OpensearchDashboardsClient)
 	if !isAssertedType {
@@ -136,14 +262,7 @@ func resourceSavedObjectsDelete(ctx context.Context, d *schema.ResourceData, m a
 		return diag
 	}
 
-	diagnostics := hc.SavedObjects.DeleteObject(ctx, req)
-	if diagnostics != nil {
-		log.Error().Msgf("could not get saved object. Terraform diagnostics: %v", diagnostics)
-
-		return diagnostics
-	}
-
-	return nil
+	return hc.deleteObject(ctx, d, req)
 }
 
 func resourceSavedObjectRead(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
@@ -157,7 +276,7 @@ func resourceSavedObjectRead(ctx context.Context, d *schema.ResourceData, m any)
 		return diagnostics
 	}
 
-	resp, diagnostics := hc.SavedObjects.GetObject(ctx, obj)
+	resp, diagnostics := hc.readObject(ctx, d, obj)
 	if diagnostics != nil {
 		return diagnostics
 	}
@@ -168,6 +287,13 @@ func resourceSavedObjectRead(ctx context.Context, d *schema.ResourceData, m any)
 		return diagnostics
 	}
 
+	if d.Get("auto_references").(bool) {
+		diagnostics = injectReferences(resp)
+		if diagnostics != nil {
+			return diagnostics
+		}
+	}
+
 	err := d.Set("type", resp.Type)
 	if err != nil {
 		return diag.FromErr(err)
@@ -178,16 +304,7 @@ func resourceSavedObjectRead(ctx context.Context, d *schema.ResourceData, m any)
 		return diag.FromErr(err)
 	}
 
-	refs := make([]any, len(resp.References))
-	for i := range resp.References {
-		a := make(map[string]any)
-		a["id"] = resp.References[i].ID
-		a["name"] = resp.References[i].Name
-		a["type"] = resp.References[i].Type
-		refs[i] = a
-	}
-
-	err = d.Set("references", refs)
+	err = d.Set("references", flattenReferences(d, hc.idNamespace, resp.References))
 	if err != nil {
 		return diag.FromErr(err)
 	}
@@ -197,6 +314,56 @@ func resourceSavedObjectRead(ctx context.Context, d *schema.ResourceData, m any)
 	return nil
 }
 
+// flattenReferences converts the references read to the schema. Without namespace the objects a reference points
+// to cannot be told apart, so external is kept as configured.
+func flattenReferences(d *schema.ResourceData, namespace *saved_objects.IDNamespace, references []saved_objects.Reference) []any {
+	external := map[saved_objects.Reference]bool{}
+	if namespace == nil {
+		for _, ref := range expandReferences(d.Get("references").(*schema.Set)) {
+			if ref.External {
+				ref.External = false
+				external[ref] = true
+			}
+		}
+	}
+
+	refs := make([]any, len(references))
+	for i, ref := range references {
+		refs[i] = map[string]any{
+			"id":       ref.ID,
+			"name":     ref.Name,
+			"type":     ref.Type,
+			"external": ref.External || external[ref],
+		}
+	}
+
+	return refs
+}
+
+// injectReferences reverses the extraction of auto_references, so that the attributes match the configuration.
+func injectReferences(obj *saved_objects.SavedObjectTF) diag.Diagnostics {
+	attrMap := make(map[string]any)
+	err := json.Unmarshal([]byte(obj.Attributes), &attrMap)
+	if err != nil {
+		return diag.Errorf("attributes is not valid json: %v", err)
+	}
+
+	attrMap, refs, err := saved_objects.InjectReferences(attrMap, obj.References)
+	if err != nil {
+		return diag.Errorf("could not inject references into attributes: %v", err)
+	}
+
+	attributes, err := json.Marshal(attrMap)
+	if err != nil {
+		return diag.Errorf("could not stringify attributes: %v", err)
+	}
+
+	obj.Attributes = string(attributes)
+	obj.References = refs
+
+	return nil
+}
+
 func resourceSavedObjectWrite(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
 	hc, isAssertedType := m.(*OpensearchDashboardsClient)
 	if !isAssertedType {
@@ -208,10 +375,25 @@ func resourceSavedObjectWrite(ctx context.Context, d *schema.ResourceData, m any
 		return diagnostics
 	}
 
-	diagnostics = hc.SavedObjects.SaveObject(ctx, req)
-	if diagnostics != nil {
-		return diagnostics
+	return append(unusedReferencesWarning(req), hc.writeObject(ctx, d, req)...)
+}
+
+// unusedReferencesWarning warns about named references of obj which are not used in its attributes. They do not
+// break the object, but usually are leftovers of a removed panel or filter.
+func unusedReferencesWarning(obj *saved_objects.SavedObjectOSD) diag.Diagnostics {
+	unused := saved_objects.UnusedReferences(obj.Attributes, obj.References)
+	if len(unused) == 0 {
+		return nil
 	}
 
-	return nil
+	descriptions := make([]string, 0, len(unused))
+	for _, ref := range unused {
+		descriptions = append(descriptions, fmt.Sprintf("%q (%s %q)", ref.Name, ref.Type, ref.ID))
+	}
+
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  fmt.Sprintf("%s %q has references which are not used in its attributes", obj.Type, obj.ID),
+		Detail:   "The following references are not used: " + strings.Join(descriptions, ", "),
+	}}
 }
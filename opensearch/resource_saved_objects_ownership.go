@@ -0,0 +1,296 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+// ownershipFilterKeys are the attributes which decide which objects are owned and which of them are managed
+var ownershipFilterKeys = []string{"types", "id_prefix", "title_regex", "tenant", "managed_ids", "require_managed_marker"}
+
+func resourceSavedObjectsOwnership() *schema.Resource {
+	return &schema.Resource{
+		Description: "Claims exclusive ownership of all saved objects matching a filter, e.g. every dashboard whose ID starts with `platform-`. " +
+			"Matching objects which are not listed in `managed_ids` are reported in `unmanaged_objects` and, with `prune`, deleted on the next apply. " +
+			"When the resource is created or its filter changes nothing is deleted, so the objects to be removed can be reviewed in the plan first.",
+		ReadContext:   resourceSavedObjectsOwnershipRead,
+		CreateContext: resourceSavedObjectsOwnershipCreate,
+		UpdateContext: resourceSavedObjectsOwnershipUpdate,
+		DeleteContext: resourceSavedObjectsOwnershipDelete,
+		CustomizeDiff: resourceSavedObjectsOwnershipCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"types": {
+				Description: "Types of the owned saved objects, e.g. `dashboard` or `visualization`.",
+				Type:        schema.TypeSet,
+				Required:    true,
+				MinItems:    1,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"id_prefix": {
+				Description:  "Only objects whose ID starts with this prefix are owned.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				AtLeastOneOf: []string{"id_prefix", "title_regex"},
+			},
+			"title_regex": {
+				Description:  "Only objects whose title matches this regular expression are owned. If `id_prefix` is set as well, both must match.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+				AtLeastOneOf: []string{"id_prefix", "title_regex"},
+			},
+			"tenant": {
+				Description: "Tenant of the security plugin to look for objects in. The tenant of the user is used if not set.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+			},
+			"managed_ids": {
+				Description: "IDs of the owned objects which are managed by Terraform, usually the `obj_id` of the corresponding resources.",
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"require_managed_marker": {
+				Description: "Only objects carrying the `managed_marker` of the provider are owned, so objects created in the UI are never pruned.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+			},
+			"prune": {
+				Description: "Delete the objects listed in `unmanaged_objects` on apply.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+			},
+			"unmanaged_objects": {
+				Description: "Owned objects which are not managed by Terraform as `type/id`, with IDs as in `obj_id`. These are the objects which are deleted if `prune` is set, so with `prune` unset this is a dry run.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+// findUnmanagedObjects returns all objects matching the filter of the resource which are not managed by Terraform.
+func findUnmanagedObjects(ctx context.Context, hc *OpensearchDashboardsClient, d *schema.ResourceData) ([]saved_objects.SavedObjectOSD, diag.Diagnostics) {
+	var titleRegex *regexp.Regexp
+	if v := d.Get("title_regex").(string); v != "" {
+		var err error
+		titleRegex, err = regexp.Compile(v)
+		if err != nil {
+			return nil, diag.Errorf("title_regex is not a valid regular expression: %v", err)
+		}
+	}
+
+	requireMarker := d.Get("require_managed_marker").(bool)
+	if requireMarker && hc.managedMarker == nil {
+		return nil, diag.Errorf("require_managed_marker is set but managed_marker is not configured in the provider")
+	}
+
+	tenant := d.Get("tenant").(string)
+	objs, err := hc.SavedObjects.FindObjects(ctx, saved_objects.FindOptions{
+		Types:  expandStringSet(d.Get("types").(*schema.Set)),
+		Tenant: tenant,
+	})
+	if err != nil {
+		return nil, errorDiagnostics(err, "could not find owned objects")
+	}
+
+	managed := map[string]bool{}
+	for _, id := range expandStringSet(d.Get("managed_ids").(*schema.Set)) {
+		managed[id] = true
+	}
+
+	idPrefix := d.Get("id_prefix").(string)
+	var unmanaged []saved_objects.SavedObjectOSD
+	namespace := hc.idNamespace
+	for _, obj := range objs {
+		// with an ID namespace only the objects in it are owned, their IDs are used as configured in obj_id
+		if namespace != nil && !namespace.Contains(obj.ID) {
+			continue
+		}
+		obj.ID = namespace.Strip(obj.ID)
+		if managed[obj.ID] || !strings.HasPrefix(obj.ID, idPrefix) {
+			continue
+		}
+		if requireMarker && !hc.managedMarker.IsMarked(obj.Type, obj.Attributes) {
+			continue
+		}
+		// the title regex is matched against the title as configured
+		hc.managedMarker.Strip(obj.Type, obj.Attributes)
+		if titleRegex != nil {
+			title, _ := obj.Attributes["title"].(string)
+			if !titleRegex.MatchString(title) {
+				continue
+			}
+		}
+		obj.Tenant = tenant
+		unmanaged = append(unmanaged, obj)
+	}
+
+	sort.Slice(unmanaged, func(i, j int) bool {
+		return formatObjectKey(unmanaged[i]) < formatObjectKey(unmanaged[j])
+	})
+
+	return unmanaged, nil
+}
+
+func formatObjectKey(obj saved_objects.SavedObjectOSD) string {
+	return obj.Type + "/" + obj.ID
+}
+
+func expandStringSet(set *schema.Set) []string {
+	result := make([]string, 0, set.Len())
+	for _, v := range set.List() {
+		result = append(result, v.(string))
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// resourceSavedObjectsOwnershipCustomizeDiff plans the removal of the unmanaged objects found by the last refresh.
+// If the filter changes, the objects are only known after apply and nothing is deleted.
+func resourceSavedObjectsOwnershipCustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ any) error {
+	if d.Id() == "" {
+		return nil
+	}
+
+	for _, key := range ownershipFilterKeys {
+		if d.HasChange(key) {
+			return d.SetNewComputed("unmanaged_objects")
+		}
+	}
+
+	if d.Get("prune").(bool) && len(d.Get("unmanaged_objects").([]any)) > 0 {
+		return d.SetNew("unmanaged_objects", []string{})
+	}
+
+	return nil
+}
+
+func resourceSavedObjectsOwnershipRead(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	unmanaged, diagnostics := findUnmanagedObjects(ctx, hc, d)
+	if diagnostics != nil {
+		return diagnostics
+	}
+
+	keys := make([]string, 0, len(unmanaged))
+	for _, obj := range unmanaged {
+		keys = append(keys, formatObjectKey(obj))
+	}
+
+	if err := d.Set("unmanaged_objects", keys); err != nil {
+		return diag.Errorf("could not set unmanaged_objects: %v", err)
+	}
+
+	return nil
+}
+
+func resourceSavedObjectsOwnershipCreate(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	d.SetId(ownershipId(d))
+
+	return resourceSavedObjectsOwnershipRead(ctx, d, m)
+}
+
+func ownershipId(d *schema.ResourceData) string {
+	id := strings.Join(expandStringSet(d.Get("types").(*schema.Set)), ",")
+	if tenant := d.Get("tenant").(string); tenant != "" {
+		id = tenant + ":" + id
+	}
+	if prefix := d.Get("id_prefix").(string); prefix != "" {
+		id += "/" + prefix
+	}
+	if titleRegex := d.Get("title_regex").(string); titleRegex != "" {
+		id += "/" + titleRegex
+	}
+
+	return id
+}
+
+// resourceSavedObjectsOwnershipUpdate deletes the unmanaged objects which were shown in the plan and are still unmanaged.
+func resourceSavedObjectsOwnershipUpdate(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	// with a changed filter the plan showed the unmanaged objects as known after apply, so nothing is deleted
+	for _, key := range ownershipFilterKeys {
+		if d.HasChange(key) {
+			return resourceSavedObjectsOwnershipRead(ctx, d, m)
+		}
+	}
+
+	if !d.Get("prune").(bool) {
+		return nil
+	}
+
+	planned, _ := d.GetChange("unmanaged_objects")
+	reviewed := map[string]bool{}
+	for _, key := range planned.([]any) {
+		reviewed[key.(string)] = true
+	}
+
+	unmanaged, diagnostics := findUnmanagedObjects(ctx, hc, d)
+	if diagnostics != nil {
+		return diagnostics
+	}
+
+	for i := range unmanaged {
+		obj := unmanaged[i]
+		if !reviewed[formatObjectKey(obj)] {
+			continue
+		}
+		if err := hc.SavedObjects.DeleteObject(ctx, &obj); err != nil {
+			tflog.Error(ctx, "could not prune object", map[string]any{"object": formatObjectKey(obj), "error": err.Error()})
+
+			return errorDiagnostics(err, "could not prune %s", formatObjectKey(obj))
+		}
+	}
+
+	// the result must match the plan, objects created since the last refresh show up on the next one
+	if err := d.Set("unmanaged_objects", []string{}); err != nil {
+		return diag.Errorf("could not set unmanaged_objects: %v", err)
+	}
+
+	return nil
+}
+
+func resourceSavedObjectsOwnershipDelete(_ context.Context, d *schema.ResourceData, _ any) diag.Diagnostics {
+	// giving up the ownership leaves all objects untouched
+	d.SetId("")
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/fakeosd"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+// applyOwnership plans the config against the state and applies the plan like Terraform does. beforeApply is
+// called between plan and apply.
+func applyOwnership(t *testing.T, hc *OpensearchDashboardsClient, state *terraform.InstanceState, config map[string]any, beforeApply func()) *terraform.InstanceState {
+	t.Helper()
+	r := resourceSavedObjectsOwnership()
+
+	diff, err := r.SimpleDiff(context.Background(), state, terraform.NewResourceConfigRaw(config), hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if beforeApply != nil {
+		beforeApply()
+	}
+	if diff.Empty() {
+		return state
+	}
+
+	state, diags := r.Apply(context.Background(), state, diff, hc)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	return state
+}
+
+func stateList(state *terraform.InstanceState, key string) []string {
+	n, _ := strconv.Atoi(state.Attributes[key+".#"])
+	result := make([]string, n)
+	for i := range result {
+		result[i] = state.Attributes[key+"."+strconv.Itoa(i)]
+	}
+
+	return result
+}
+
+func TestSavedObjectsOwnership(t *testing.T) {
+	objects := []fakeosd.Object{
+		{Type: "dashboard", ID: "platform-managed", Attributes: map[string]any{"title": "Managed [terraform]"}},
+		{Type: "dashboard", ID: "platform-stale", Attributes: map[string]any{"title": "Stale [terraform]"}},
+		{Type: "dashboard", ID: "platform-manual", Attributes: map[string]any{"title": "Manual"}},
+		{Type: "dashboard", ID: "team-other", Attributes: map[string]any{"title": "Other"}},
+	}
+	base := map[string]any{
+		"types":       []any{"dashboard"},
+		"id_prefix":   "platform-",
+		"managed_ids": []any{"platform-managed"},
+	}
+
+	testCases := []struct {
+		desc string
+		// config is merged into base for the creation, update is merged into config for the following apply
+		config        map[string]any
+		update        map[string]any
+		wantUnmanaged []string
+		wantDeleted   []string
+	}{
+		{
+			desc:          "must only report unmanaged objects without prune",
+			wantUnmanaged: []string{"dashboard/platform-manual", "dashboard/platform-stale"},
+		},
+		{
+			desc:          "must prune the objects shown in the plan",
+			update:        map[string]any{"prune": true},
+			wantUnmanaged: []string{},
+			wantDeleted:   []string{"platform-manual", "platform-stale"},
+		},
+		{
+			desc:          "must not prune when the filter changes",
+			config:        map[string]any{"prune": true},
+			update:        map[string]any{"managed_ids": []any{}},
+			wantUnmanaged: []string{"dashboard/platform-managed", "dashboard/platform-manual", "dashboard/platform-new", "dashboard/platform-stale"},
+		},
+		{
+			desc:          "must only prune objects with the managed marker",
+			config:        map[string]any{"require_managed_marker": true},
+			update:        map[string]any{"prune": true},
+			wantUnmanaged: []string{},
+			wantDeleted:   []string{"platform-stale"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := fakeosd.New(fakeosd.Options{})
+			defer s.Close()
+			for _, obj := range objects {
+				s.Put("", obj)
+			}
+
+			hc := &OpensearchDashboardsClient{
+				SavedObjects:  saved_objects.NewSavedObjectsProvider(s.URL, http.DefaultClient, false),
+				managedMarker: &saved_objects.ManagedMarker{TitleSuffix: " [terraform]"},
+			}
+			config := maps.Clone(base)
+			maps.Copy(config, tC.config)
+			state := applyOwnership(t, hc, nil, config, nil)
+			state, diags := resourceSavedObjectsOwnership().RefreshWithoutUpgrade(context.Background(), state, hc)
+			if diags.HasError() {
+				t.Fatal(diags)
+			}
+
+			maps.Copy(config, tC.update)
+			// objects created between plan and apply were not reviewed, so they must never be pruned
+			state = applyOwnership(t, hc, state, config, func() {
+				s.Put("", fakeosd.Object{Type: "dashboard", ID: "platform-new", Attributes: map[string]any{"title": "New"}})
+			})
+
+			if got := stateList(state, "unmanaged_objects"); !reflect.DeepEqual(got, tC.wantUnmanaged) {
+				t.Errorf("expected unmanaged objects %v but got %v", tC.wantUnmanaged, got)
+			}
+			deleted := map[string]bool{}
+			for _, id := range tC.wantDeleted {
+				deleted[id] = true
+			}
+			for _, obj := range append(objects, fakeosd.Object{Type: "dashboard", ID: "platform-new"}) {
+				if _, exists := s.Get("", obj.Type, obj.ID); exists == deleted[obj.ID] {
+					t.Errorf("expected %s to be deleted %v but it exists %v", obj.ID, deleted[obj.ID], exists)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/fakeosd"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+func testAccSavedObjectConfig(provider, title string) string {
+	return provider + fmt.Sprintf(`
+resource "opensearch_saved_object" "pattern" {
+  obj_id     = "acc-pattern"
+  type       = "index-pattern"
+  attributes = jsonencode({ title = "logs-*", timeFieldName = "@timestamp" })
+}
+
+resource "opensearch_saved_object" "search" {
+  obj_id = "acc-search"
+  type   = "search"
+  attributes = jsonencode({
+    title   = %q
+    columns = ["message"]
+    kibanaSavedObjectMeta = {
+      searchSourceJSON = jsonencode({ indexRefName = "kibanaSavedObjectMeta.searchSourceJSON.index" })
+    }
+  })
+  references {
+    id   = opensearch_saved_object.pattern.obj_id
+    name = "kibanaSavedObjectMeta.searchSourceJSON.index"
+    type = "index-pattern"
+  }
+}
+`, title)
+}
+
+// testAccCheckTitle checks the title of the object on the server.
+func testAccCheckTitle(s *fakeosd.Server, objType, id, want string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		obj, ok := s.Get("", objType, id)
+		if !ok {
+			return fmt.Errorf("%s %q does not exist", objType, id)
+		}
+		if title := obj.Attributes["title"]; title != want {
+			return fmt.Errorf("expected title %q of %s %q but got %q", want, objType, id, title)
+		}
+
+		return nil
+	}
+}
+
+func TestAccSavedObject(t *testing.T) {
+	s, provider := testAccServer(t, fakeosd.Profile2)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			for _, obj := range []struct{ Type, ID string }{{"index-pattern", "acc-pattern"}, {"search", "acc-search"}} {
+				if _, ok := s.Get("", obj.Type, obj.ID); ok {
+					return fmt.Errorf("%s %q still exists after destroy", obj.Type, obj.ID)
+				}
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccSavedObjectConfig(provider, "Errors"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("opensearch_saved_object.search", "obj_id", "acc-search"),
+					resource.TestCheckResourceAttr("opensearch_saved_object.search", "references.#", "1"),
+					testAccCheckTitle(s, "index-pattern", "acc-pattern", "logs-*"),
+					testAccCheckTitle(s, "search", "acc-search", "Errors"),
+				),
+			},
+			{
+				Config: testAccSavedObjectConfig(provider, "All errors"),
+				Check:  testAccCheckTitle(s, "search", "acc-search", "All errors"),
+			},
+			{
+				ResourceName:      "opensearch_saved_object.search",
+				ImportState:       true,
+				ImportStateId:     "search/acc-search",
+				ImportStateVerify: true,
+			},
+			{
+				// an edit in the UI is detected as drift
+				PreConfig: func() {
+					obj, _ := s.Get("", "search", "acc-search")
+					obj.Attributes["title"] = "Edited in the UI"
+					s.Put("", obj)
+				},
+				Config:             testAccSavedObjectConfig(provider, "All errors"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccSavedObjectConfig(provider, "All errors"),
+				Check:  testAccCheckTitle(s, "search", "acc-search", "All errors"),
+			},
+			{
+				// an object deleted in the UI is recreated
+				PreConfig: func() {
+					s.Delete("", "search", "acc-search")
+				},
+				Config:             testAccSavedObjectConfig(provider, "All errors"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccSavedObjectConfig(provider, "All errors"),
+				Check:  testAccCheckTitle(s, "search", "acc-search", "All errors"),
+			},
+		},
+	})
+}
+
+func TestAccSavedObjectUnsupportedType(t *testing.T) {
+	_, provider := testAccServer(t, fakeosd.Profile1)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: provider + `
+resource "opensearch_saved_object" "builder" {
+  obj_id     = "acc-builder"
+  type       = "visualization-visbuilder"
+  attributes = jsonencode({ title = "Builder" })
+}
+`,
+				ExpectError: regexp.MustCompile(`not supported by OpenSearch Dashboards 1\.3\.6`),
+			},
+		},
+	})
+}
+
+// unknownValue marks a value in a raw config as unknown, like values which are only known after apply.
+const unknownValue = "74D93920-ED26-11E3-AC10-0800200C9A66"
+
+func TestSavedObjectCustomizeDiff(t *testing.T) {
+	attributes := `{"title":"Errors","kibanaSavedObjectMeta":{"searchSourceJSON":"{\"indexRefName\":\"kibanaSavedObjectMeta.searchSourceJSON.index\"}"}}`
+	testCases := []struct {
+		desc      string
+		config    map[string]any
+		marker    *saved_objects.ManagedMarker
+		wantError string
+	}{
+		{
+			desc: "must accept matching references",
+			config: map[string]any{
+				"attributes": attributes,
+				"references": []any{map[string]any{"id": "pattern", "name": "kibanaSavedObjectMeta.searchSourceJSON.index", "type": "index-pattern"}},
+			},
+		},
+		{
+			desc: "must accept unused references",
+			config: map[string]any{
+				"attributes": attributes,
+				"references": []any{
+					map[string]any{"id": "pattern", "name": "kibanaSavedObjectMeta.searchSourceJSON.index", "type": "index-pattern"},
+					map[string]any{"id": "search", "name": "search_0", "type": "search"},
+				},
+			},
+		},
+		{
+			desc:      "must reject missing references",
+			config:    map[string]any{"attributes": attributes},
+			wantError: "do not match its attributes",
+		},
+		{
+			desc:      "must reject titles with the managed marker",
+			config:    map[string]any{"attributes": `{"title":"Errors [terraform]"}`},
+			marker:    &saved_objects.ManagedMarker{TitleSuffix: " [terraform]"},
+			wantError: "must not end with the managed marker",
+		},
+		{
+			desc:   "must skip unknown references",
+			config: map[string]any{"attributes": attributes, "references": unknownValue},
+		},
+		{
+			desc: "must skip unknown attributes",
+			config: map[string]any{
+				"attributes": unknownValue,
+				"references": []any{map[string]any{"id": "pattern", "name": "unused", "type": "index-pattern"}},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			tC.config["obj_id"] = "errors"
+			tC.config["type"] = "search"
+
+			hc := &OpensearchDashboardsClient{managedMarker: tC.marker}
+			_, err := resourceSavedObjects().SimpleDiff(context.Background(), nil, terraform.NewResourceConfigRaw(tC.config), hc)
+			if tC.wantError == "" {
+				if err != nil {
+					t.Errorf("expected no error but got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tC.wantError) {
+				t.Errorf("expected error %q but got %v", tC.wantError, err)
+			}
+		})
+	}
+}
+
+func TestSavedObjectWriteUnusedReferences(t *testing.T) {
+	s := fakeosd.New(fakeosd.Options{})
+	defer s.Close()
+
+	hc := &OpensearchDashboardsClient{SavedObjects: saved_objects.NewSavedObjectsProvider(s.URL, http.DefaultClient, false)}
+	d := schema.TestResourceDataRaw(t, resourceSavedObjects().Schema, map[string]any{
+		"obj_id":     "errors",
+		"type":       "search",
+		"attributes": `{"title":"Errors"}`,
+		"references": []any{map[string]any{"id": "pattern", "name": "search_0", "type": "index-pattern"}},
+	})
+
+	diags := resourceSavedObjectWrite(context.Background(), d, hc)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, `"search_0"`) {
+		t.Errorf("expected a warning about the unused reference but got %v", diags)
+	}
+	if _, ok := s.Get("", "search", "errors"); !ok {
+		t.Error("expected the object to be saved")
+	}
+}
@@ -0,0 +1,640 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/visualization"
+)
+
+// tsvbField maps an attribute of the schema to a key of the TSVB params. TSVB stores flags as 0 and 1,
+// which is why booleans are converted.
+type tsvbField struct {
+	attribute string
+	key       string
+	boolAsInt bool
+}
+
+var (
+	tsvbPanelFields = []tsvbField{
+		{attribute: "panel_type", key: "type"},
+		{attribute: "index_pattern", key: "index_pattern"},
+		{attribute: "time_field", key: "time_field"},
+		{attribute: "interval", key: "interval"},
+		{attribute: "drop_last_bucket", key: "drop_last_bucket", boolAsInt: true},
+		{attribute: "show_legend", key: "show_legend", boolAsInt: true},
+		{attribute: "show_grid", key: "show_grid", boolAsInt: true},
+		{attribute: "axis_position", key: "axis_position"},
+		{attribute: "tooltip_mode", key: "tooltip_mode"},
+		{attribute: "background_color", key: "background_color"},
+	}
+	tsvbSeriesFields = []tsvbField{
+		{attribute: "id", key: "id"},
+		{attribute: "label", key: "label"},
+		{attribute: "color", key: "color"},
+		{attribute: "chart_type", key: "chart_type"},
+		{attribute: "line_width", key: "line_width"},
+		{attribute: "point_size", key: "point_size"},
+		{attribute: "fill", key: "fill"},
+		{attribute: "stacked", key: "stacked"},
+		{attribute: "split_mode", key: "split_mode"},
+		{attribute: "terms_field", key: "terms_field"},
+		{attribute: "terms_size", key: "terms_size"},
+		{attribute: "formatter", key: "formatter"},
+		{attribute: "separate_axis", key: "separate_axis", boolAsInt: true},
+		{attribute: "axis_position", key: "axis_position"},
+		{attribute: "override_index_pattern", key: "override_index_pattern", boolAsInt: true},
+		{attribute: "series_index_pattern", key: "series_index_pattern"},
+		{attribute: "series_time_field", key: "series_time_field"},
+	}
+	tsvbMetricFields = []tsvbField{
+		{attribute: "id", key: "id"},
+		{attribute: "type", key: "type"},
+		{attribute: "field", key: "field"},
+	}
+)
+
+func resourceTSVBVisualization() *schema.Resource {
+	return addTenantsSchema(addDeletionProtectionSchema(&schema.Resource{
+		Description:   "Manages a Time Series Visual Builder (TSVB) visualization with structured panel, series and metric options. The panel parameters are validated at plan time.",
+		ReadContext:   resourceTSVBVisualizationRead,
+		CreateContext: resourceTSVBVisualizationWrite,
+		UpdateContext: resourceTSVBVisualizationWrite,
+		DeleteContext: resourceVisualizationDelete,
+		CustomizeDiff: resourceTSVBVisualizationCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: map[string]*schema.Schema{
+			"obj_id": {
+				Description: "ID of the saved object.",
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+			},
+			"title": {
+				Description: "Title of the visualization.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"description": {
+				Description: "Description of the visualization.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"panel_type": {
+				Description:  "Type of the panel, one of `timeseries`, `metric`, `top_n`, `gauge`, `markdown` or `table`.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice(visualization.TSVBPanelTypes, false),
+			},
+			"index_pattern": {
+				Description: "Index pattern string (not the ID of an index pattern saved object) the panel queries, for example `logs-*`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"time_field": {
+				Description: "Time field of the index pattern.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"interval": {
+				Description: "Interval of the panel, for example `auto` or `1h`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "auto",
+			},
+			"drop_last_bucket": {
+				Description: "Whether the last, incomplete bucket is dropped.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+			},
+			"show_legend": {
+				Description: "Whether the legend is shown.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+			},
+			"show_grid": {
+				Description: "Whether the grid is shown.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+			},
+			"axis_position": {
+				Description:  "Position of the axis, either `left` or `right`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "left",
+				ValidateFunc: validation.StringInSlice([]string{"left", "right"}, false),
+			},
+			"tooltip_mode": {
+				Description:  "Tooltip mode, either `show_all` or `show_focused`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "show_all",
+				ValidateFunc: validation.StringInSlice([]string{"show_all", "show_focused"}, false),
+			},
+			"background_color": {
+				Description: "Background color of the panel.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"filter": {
+				Description: "Query filtering the data of the panel.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"filter_language": {
+				Description:  "Language of `filter`, either `kuery` or `lucene`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      visualization.DefaultQueryLanguage,
+				ValidateFunc: validation.StringInSlice([]string{"kuery", "lucene"}, false),
+			},
+			"params_json": {
+				Description:      "Further panel parameters as stringified JSON object. Attributes of this resource take precedence.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "{}",
+				ValidateFunc:     validation.StringIsJSON,
+				DiffSuppressFunc: structure.SuppressJsonDiff,
+			},
+			"series": {
+				Description: "Series of the panel.",
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Elem:        resourceTSVBSeries(),
+			},
+		},
+	}))
+}
+
+func resourceTSVBSeries() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Description: "ID of the series, unique within the panel.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"label": {
+				Description: "Label of the series.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"color": {
+				Description: "Color of the series.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "#54B399",
+			},
+			"chart_type": {
+				Description:  "Chart type of the series, either `line` or `bar`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "line",
+				ValidateFunc: validation.StringInSlice([]string{"line", "bar"}, false),
+			},
+			"line_width": {
+				Description: "Line width of the series.",
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     1,
+			},
+			"point_size": {
+				Description: "Point size of the series.",
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     1,
+			},
+			"fill": {
+				Description: "Opacity of the fill of the series between 0 and 1.",
+				Type:        schema.TypeFloat,
+				Optional:    true,
+				Default:     0.5,
+			},
+			"stacked": {
+				Description:  "Stacking of the series, one of `none`, `stacked` or `percent`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "none",
+				ValidateFunc: validation.StringInSlice([]string{"none", "stacked", "percent"}, false),
+			},
+			"split_mode": {
+				Description:  "How the series is split, one of `everything`, `terms`, `filter` or `filters`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "everything",
+				ValidateFunc: validation.StringInSlice([]string{"everything", "terms", "filter", "filters"}, false),
+			},
+			"terms_field": {
+				Description: "Field the series is split by if `split_mode` is `terms`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"terms_size": {
+				Description: "Number of terms if `split_mode` is `terms`.",
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     10,
+			},
+			"formatter": {
+				Description: "Formatter of the values, for example `number`, `bytes` or `percent`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "number",
+			},
+			"separate_axis": {
+				Description: "Whether the series has its own axis.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+			},
+			"axis_position": {
+				Description:  "Position of the separate axis, either `left` or `right`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "right",
+				ValidateFunc: validation.StringInSlice([]string{"left", "right"}, false),
+			},
+			"override_index_pattern": {
+				Description: "Whether the series queries `series_index_pattern` instead of the index pattern of the panel.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+			},
+			"series_index_pattern": {
+				Description: "Index pattern string of the series if `override_index_pattern` is set.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"series_time_field": {
+				Description: "Time field of the series if `override_index_pattern` is set.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"extra_json": {
+				Description:      "Further options of the series as stringified JSON object.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "{}",
+				ValidateFunc:     validation.StringIsJSON,
+				DiffSuppressFunc: structure.SuppressJsonDiff,
+			},
+			"metric": {
+				Description: "Metrics of the series. The last metric is the one that is shown.",
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Description: "ID of the metric, unique within the series.",
+							Type:        schema.TypeString,
+							Required:    true,
+						},
+						"type": {
+							Description:  "Type of the metric, for example `count`, `avg` or `derivative`.",
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringInSlice(visualization.TSVBMetricTypes, false),
+						},
+						"field": {
+							Description: "Field the metric aggregates. For pipeline aggregations this is the ID of another metric.",
+							Type:        schema.TypeString,
+							Optional:    true,
+						},
+						"extra_json": {
+							Description:      "Further options of the metric as stringified JSON object.",
+							Type:             schema.TypeString,
+							Optional:         true,
+							Default:          "{}",
+							ValidateFunc:     validation.StringIsJSON,
+							DiffSuppressFunc: structure.SuppressJsonDiff,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// resourceGetter is implemented by schema.ResourceData and schema.ResourceDiff.
+type resourceGetter interface {
+	Get(key string) any
+}
+
+// expandTSVBParams returns the TSVB params of the resource. known reports whether the value of a key is known yet,
+// values which are not are expanded as visualization.Unknown. Once planned all values are known and known is nil.
+func expandTSVBParams(d resourceGetter, known func(key string) bool) (map[string]any, error) {
+	if known == nil {
+		known = func(string) bool { return true }
+	}
+
+	params := map[string]any{}
+	if err := json.Unmarshal([]byte(d.Get("params_json").(string)), &params); err != nil {
+		return nil, fmt.Errorf("params_json is not a valid JSON object: %w", err)
+	}
+
+	panel := map[string]any{}
+	for _, f := range tsvbPanelFields {
+		panel[f.attribute] = knownValue(d, known, f.attribute)
+	}
+	expandTSVBFields(params, panel, tsvbPanelFields)
+
+	params["id"] = d.Get("obj_id").(string)
+	params["filter"] = map[string]any{
+		"query":    d.Get("filter").(string),
+		"language": d.Get("filter_language").(string),
+	}
+
+	var series []any
+	for i, sAny := range d.Get("series").([]any) {
+		sMap := sAny.(map[string]any)
+		sKey := fmt.Sprintf("series.%d", i)
+		s := map[string]any{}
+		// the keys of extra_json which are covered by the schema are overwritten, so unknown extra_json is left out
+		if known(sKey + ".extra_json") {
+			if err := json.Unmarshal([]byte(sMap["extra_json"].(string)), &s); err != nil {
+				return nil, fmt.Errorf("series.%d.extra_json is not a valid JSON object: %w", i, err)
+			}
+		}
+		expandTSVBFields(s, markUnknown(sMap, known, sKey, tsvbSeriesFields), tsvbSeriesFields)
+
+		var metrics []any
+		for j, mAny := range sMap["metric"].([]any) {
+			mMap := mAny.(map[string]any)
+			mKey := fmt.Sprintf("%s.metric.%d", sKey, j)
+			metric := map[string]any{}
+			if known(mKey + ".extra_json") {
+				if err := json.Unmarshal([]byte(mMap["extra_json"].(string)), &metric); err != nil {
+					return nil, fmt.Errorf("series.%d.metric.%d.extra_json is not a valid JSON object: %w", i, j, err)
+				}
+			}
+			expandTSVBFields(metric, markUnknown(mMap, known, mKey, tsvbMetricFields), tsvbMetricFields)
+			metrics = append(metrics, metric)
+		}
+		s["metrics"] = metrics
+		if !known(sKey + ".metric.#") {
+			s["metrics"] = visualization.Unknown
+		}
+
+		series = append(series, s)
+	}
+	params["series"] = series
+
+	return params, nil
+}
+
+func knownValue(d resourceGetter, known func(key string) bool, key string) any {
+	if !known(key) {
+		return visualization.Unknown
+	}
+	return d.Get(key)
+}
+
+// markUnknown returns a copy of the values of a list element in which the values of the fields which are not known
+// yet are replaced with visualization.Unknown.
+func markUnknown(values map[string]any, known func(key string) bool, prefix string, fields []tsvbField) map[string]any {
+	result := make(map[string]any, len(values))
+	for k, v := range values {
+		result[k] = v
+	}
+	for _, f := range fields {
+		if !known(prefix + "." + f.attribute) {
+			result[f.attribute] = visualization.Unknown
+		}
+	}
+	return result
+}
+
+func expandTSVBFields(dst, src map[string]any, fields []tsvbField) {
+	for _, f := range fields {
+		v := src[f.attribute]
+		if b, ok := v.(bool); ok && f.boolAsInt {
+			v = 0
+			if b {
+				v = 1
+			}
+		}
+		dst[f.key] = v
+	}
+}
+
+// flattenTSVBFields is the inverse of expandTSVBFields. It returns the values of the schema and removes
+// them from src, so that src only contains the parameters that are not covered by the schema.
+func flattenTSVBFields(src map[string]any, fields []tsvbField) map[string]any {
+	result := map[string]any{}
+	for _, f := range fields {
+		v, ok := src[f.key]
+		if !ok {
+			continue
+		}
+		delete(src, f.key)
+		if f.boolAsInt {
+			switch n := v.(type) {
+			case float64:
+				v = n != 0
+			case int:
+				v = n != 0
+			}
+		}
+		result[f.attribute] = v
+	}
+	return result
+}
+
+func flattenTSVBParams(params map[string]any) (map[string]any, error) {
+	rest := make(map[string]any, len(params))
+	for k, v := range params {
+		rest[k] = v
+	}
+
+	values := flattenTSVBFields(rest, tsvbPanelFields)
+	delete(rest, "id")
+
+	if filter, ok := rest["filter"].(map[string]any); ok {
+		delete(rest, "filter")
+		values["filter"], _ = filter["query"].(string)
+		if language, _ := filter["language"].(string); language != "" {
+			values["filter_language"] = language
+		}
+	}
+
+	seriesList, _ := rest["series"].([]any)
+	delete(rest, "series")
+	var series []any
+	for _, sAny := range seriesList {
+		sMap, ok := sAny.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("series must be a list of objects, got %T", sAny)
+		}
+		sRest := make(map[string]any, len(sMap))
+		for k, v := range sMap {
+			sRest[k] = v
+		}
+		s := flattenTSVBFields(sRest, tsvbSeriesFields)
+
+		metricList, _ := sRest["metrics"].([]any)
+		delete(sRest, "metrics")
+		var metrics []any
+		for _, mAny := range metricList {
+			mMap, ok := mAny.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("metrics must be a list of objects, got %T", mAny)
+			}
+			mRest := make(map[string]any, len(mMap))
+			for k, v := range mMap {
+				mRest[k] = v
+			}
+			metric := flattenTSVBFields(mRest, tsvbMetricFields)
+			extra, err := json.Marshal(mRest)
+			if err != nil {
+				return nil, fmt.Errorf("could not encode options of metric: %w", err)
+			}
+			metric["extra_json"] = string(extra)
+			metrics = append(metrics, metric)
+		}
+		s["metric"] = metrics
+
+		extra, err := json.Marshal(sRest)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode options of series: %w", err)
+		}
+		s["extra_json"] = string(extra)
+		series = append(series, s)
+	}
+	values["series"] = series
+
+	extra, err := json.Marshal(rest)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode params: %w", err)
+	}
+	values["params_json"] = string(extra)
+
+	return values, nil
+}
+
+func resourceTSVBVisualizationCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m any) error {
+	if err := resourceVisualizationCustomizeDiff(ctx, d, m); err != nil {
+		return err
+	}
+
+	// without the params or the list of series there is nothing to validate yet, values of single elements which are
+	// not known yet are skipped by the validation
+	for _, key := range []string{"params_json", "series.#"} {
+		if !d.NewValueKnown(key) {
+			return nil
+		}
+	}
+
+	params, err := expandTSVBParams(d, d.NewValueKnown)
+	if err != nil {
+		return err
+	}
+
+	if err := visualization.ValidateTSVBParams(params); err != nil {
+		return fmt.Errorf("invalid TSVB params: %w", err)
+	}
+
+	return nil
+}
+
+func resourceTSVBVisualizationRead(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	resp, diagnostics := hc.readObject(ctx, d, &saved_objects.SavedObjectOSD{Type: "visualization", ID: d.Id()})
+	if diagnostics != nil {
+		return diagnostics
+	}
+
+	if resp == nil {
+		// signals the resource must be (re)created
+		d.SetId("")
+		return nil
+	}
+
+	vis, err := visualization.FromSavedObject(resp)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	values, err := flattenTSVBParams(vis.Params)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	values["obj_id"] = vis.ID
+	values["title"] = vis.Title
+	values["description"] = vis.Description
+
+	for key, v := range values {
+		if err := d.Set(key, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	d.SetId(vis.ID)
+
+	return nil
+}
+
+func resourceTSVBVisualizationWrite(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	params, err := expandTSVBParams(d, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	vis := visualization.NewTSVBVisualization(
+		d.Get("obj_id").(string),
+		d.Get("title").(string),
+		d.Get("description").(string),
+		params,
+	)
+
+	req, err := vis.ToSavedObject()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	diagnostics := hc.writeObject(ctx, d, req)
+	if diagnostics.HasError() {
+		return diagnostics
+	}
+
+	d.SetId(req.ID)
+
+	return diagnostics
+}
@@ -0,0 +1,89 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestTSVBVisualizationCustomizeDiff(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		series    map[string]any
+		metric    map[string]any
+		wantError string
+	}{
+		{
+			desc:   "must accept valid params",
+			series: map[string]any{"id": "s1", "split_mode": "terms", "terms_field": "service"},
+			metric: map[string]any{"id": "m1", "type": "avg", "field": "latency"},
+		},
+		{
+			desc:      "must reject a terms split without field",
+			series:    map[string]any{"id": "s1", "split_mode": "terms"},
+			metric:    map[string]any{"id": "m1", "type": "count"},
+			wantError: "terms_field: must be set",
+		},
+		{
+			desc:      "must reject a metric without field",
+			series:    map[string]any{"id": "s1"},
+			metric:    map[string]any{"id": "m1", "type": "avg"},
+			wantError: "field: must be set",
+		},
+		{
+			desc:   "must skip unknown values of the series",
+			series: map[string]any{"id": unknownValue, "split_mode": "terms", "terms_field": unknownValue},
+			metric: map[string]any{"id": "m1", "type": "count"},
+		},
+		{
+			desc:   "must skip unknown values of the metric",
+			series: map[string]any{"id": "s1"},
+			metric: map[string]any{"id": unknownValue, "type": "avg", "field": unknownValue},
+		},
+		{
+			desc:   "must skip unknown extra_json",
+			series: map[string]any{"id": "s1", "extra_json": unknownValue},
+			metric: map[string]any{"id": "m1", "type": "count", "extra_json": unknownValue},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			tC.series["metric"] = []any{tC.metric}
+			config := map[string]any{
+				"obj_id":     "latency",
+				"title":      "Latency",
+				"panel_type": "timeseries",
+				"series":     []any{tC.series},
+			}
+
+			_, err := resourceTSVBVisualization().SimpleDiff(context.Background(), nil, terraform.NewResourceConfigRaw(config), &OpensearchDashboardsClient{})
+			if tC.wantError == "" {
+				if err != nil {
+					t.Errorf("expected no error but got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tC.wantError) {
+				t.Errorf("expected error %q but got %v", tC.wantError, err)
+			}
+		})
+	}
+}
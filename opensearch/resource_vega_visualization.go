@@ -0,0 +1,155 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/visualization"
+)
+
+func resourceVegaVisualization() *schema.Resource {
+	return addTenantsSchema(addDeletionProtectionSchema(&schema.Resource{
+		Description:   "Manages a Vega visualization. The spec is validated at plan time, so broken specs are caught before they are applied.",
+		ReadContext:   resourceVegaVisualizationRead,
+		CreateContext: resourceVegaVisualizationWrite,
+		UpdateContext: resourceVegaVisualizationWrite,
+		DeleteContext: resourceVisualizationDelete,
+		CustomizeDiff: resourceVisualizationCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: map[string]*schema.Schema{
+			"obj_id": {
+				Description: "ID of the saved object.",
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+			},
+			"title": {
+				Description: "Title of the visualization.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"description": {
+				Description: "Description of the visualization.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"spec": {
+				Description:      "Vega or Vega-Lite spec as JSON or HJSON. Use `jsonencode` to write the spec in HCL. `$schema` must reference Vega or Vega-Lite and every data url querying OpenSearch must have an `index` and, if set, an object as `body`.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validateVegaSpec,
+			},
+		},
+	}))
+}
+
+func validateVegaSpec(v any, path cty.Path) diag.Diagnostics {
+	spec, ok := v.(string)
+	if !ok {
+		return diag.Errorf("expected spec to be a string, got %T", v)
+	}
+
+	if err := visualization.ValidateVegaSpec(spec); err != nil {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "invalid Vega spec",
+			Detail:        err.Error(),
+			AttributePath: path,
+		}}
+	}
+
+	return nil
+}
+
+func resourceVegaVisualizationRead(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	resp, diagnostics := hc.readObject(ctx, d, &saved_objects.SavedObjectOSD{Type: "visualization", ID: d.Id()})
+	if diagnostics != nil {
+		return diagnostics
+	}
+
+	if resp == nil {
+		// signals the resource must be (re)created
+		d.SetId("")
+		return nil
+	}
+
+	vis, err := visualization.FromSavedObject(resp)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	spec, err := vis.VegaSpec()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	values := map[string]any{
+		"obj_id":      vis.ID,
+		"title":       vis.Title,
+		"description": vis.Description,
+		"spec":        spec,
+	}
+	for key, v := range values {
+		if err := d.Set(key, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	d.SetId(vis.ID)
+
+	return nil
+}
+
+func resourceVegaVisualizationWrite(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	vis := visualization.NewVegaVisualization(
+		d.Get("obj_id").(string),
+		d.Get("title").(string),
+		d.Get("description").(string),
+		d.Get("spec").(string),
+	)
+
+	req, err := vis.ToSavedObject()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	diagnostics := hc.writeObject(ctx, d, req)
+	if diagnostics.HasError() {
+		return diagnostics
+	}
+
+	d.SetId(req.ID)
+
+	return diagnostics
+}
@@ -0,0 +1,384 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/visualization"
+)
+
+var visualizationTypes = []string{
+	"area",
+	"gauge",
+	"goal",
+	"heatmap",
+	"histogram",
+	"horizontal_bar",
+	"input_control_vis",
+	"line",
+	"markdown",
+	"metric",
+	"pie",
+	"region_map",
+	"table",
+	"tagcloud",
+	"tile_map",
+}
+
+func resourceVisualization() *schema.Resource {
+	return addTenantsSchema(addDeletionProtectionSchema(&schema.Resource{
+		Description:   "Manages a visualization of one of the standard (aggregation based) visualization types. The provider assembles visState, uiStateJSON and kibanaSavedObjectMeta as well as the references of the saved object.",
+		ReadContext:   resourceVisualizationRead,
+		CreateContext: resourceVisualizationWrite,
+		UpdateContext: resourceVisualizationWrite,
+		DeleteContext: resourceVisualizationDelete,
+		CustomizeDiff: resourceVisualizationCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: map[string]*schema.Schema{
+			"obj_id": {
+				Description: "ID of the saved object.",
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+			},
+			"title": {
+				Description: "Title of the visualization.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"description": {
+				Description: "Description of the visualization.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"type": {
+				Description:  "Type of the visualization, for example `table`, `line`, `histogram` or `pie`.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice(visualizationTypes, false),
+			},
+			"aggregation": {
+				Description: "Aggregations of the visualization in the order in which they are shown.",
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Description: "ID of the aggregation, unique within the visualization.",
+							Type:        schema.TypeString,
+							Required:    true,
+						},
+						"type": {
+							Description: "Type of the aggregation, for example `count`, `terms` or `date_histogram`.",
+							Type:        schema.TypeString,
+							Required:    true,
+						},
+						"schema": {
+							Description: "Schema the aggregation is used for, for example `metric`, `segment` or `bucket`.",
+							Type:        schema.TypeString,
+							Optional:    true,
+						},
+						"enabled": {
+							Description: "Whether the aggregation is enabled.",
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     true,
+						},
+						"params": {
+							Description: "Parameters of the aggregation with string values. Merged into `params_json`.",
+							Type:        schema.TypeMap,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+						},
+						"params_json": {
+							Description:      "Parameters of the aggregation as stringified JSON object. Use this for non-string values.",
+							Type:             schema.TypeString,
+							Optional:         true,
+							Default:          "{}",
+							ValidateFunc:     validation.StringIsJSON,
+							DiffSuppressFunc: structure.SuppressJsonDiff,
+						},
+					},
+				},
+			},
+			"params": {
+				Description:      "Parameters of the visualization (visState.params) as stringified JSON object.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "{}",
+				ValidateFunc:     validation.StringIsJSON,
+				DiffSuppressFunc: structure.SuppressJsonDiff,
+			},
+			"ui_state": {
+				Description:      "UI state of the visualization (uiStateJSON) as stringified JSON object.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "{}",
+				ValidateFunc:     validation.StringIsJSON,
+				DiffSuppressFunc: structure.SuppressJsonDiff,
+			},
+			"saved_search_id": {
+				Description:   "ID of the saved search the visualization is based on.",
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"index_pattern_id"},
+			},
+			"index_pattern_id": {
+				Description:   "ID of the index pattern the visualization is based on.",
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"saved_search_id"},
+			},
+			"query": {
+				Description: "Query of the visualization.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"query_language": {
+				Description:  "Language of `query`, either `kuery` or `lucene`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      visualization.DefaultQueryLanguage,
+				ValidateFunc: validation.StringInSlice([]string{"kuery", "lucene"}, false),
+			},
+			"filters": {
+				Description:      "Filters of the visualization as stringified JSON array. The index pattern in `meta.index` of a filter is moved to the references.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "[]",
+				ValidateFunc:     validation.StringIsJSON,
+				DiffSuppressFunc: structure.SuppressJsonDiff,
+			},
+		},
+	}))
+}
+
+func resourceVisualizationToRequest(d *schema.ResourceData) (*saved_objects.SavedObjectOSD, diag.Diagnostics) {
+	vis := &visualization.Visualization{
+		ID:             d.Get("obj_id").(string),
+		Title:          d.Get("title").(string),
+		Description:    d.Get("description").(string),
+		Type:           d.Get("type").(string),
+		SavedSearchID:  d.Get("saved_search_id").(string),
+		IndexPatternID: d.Get("index_pattern_id").(string),
+		Query:          d.Get("query").(string),
+		QueryLanguage:  d.Get("query_language").(string),
+	}
+
+	if err := json.Unmarshal([]byte(d.Get("params").(string)), &vis.Params); err != nil {
+		return nil, diag.Errorf("params is not a valid JSON object: %v", err)
+	}
+	if err := json.Unmarshal([]byte(d.Get("ui_state").(string)), &vis.UIState); err != nil {
+		return nil, diag.Errorf("ui_state is not a valid JSON object: %v", err)
+	}
+	if err := json.Unmarshal([]byte(d.Get("filters").(string)), &vis.Filters); err != nil {
+		return nil, diag.Errorf("filters is not a valid JSON array: %v", err)
+	}
+
+	for i, aAny := range d.Get("aggregation").([]any) {
+		aMap := aAny.(map[string]any)
+		params := map[string]any{}
+		if err := json.Unmarshal([]byte(aMap["params_json"].(string)), &params); err != nil {
+			return nil, diag.Errorf("aggregation.%d.params_json is not a valid JSON object: %v", i, err)
+		}
+		for k, v := range aMap["params"].(map[string]any) {
+			params[k] = v
+		}
+
+		vis.Aggregations = append(vis.Aggregations, visualization.Aggregation{
+			ID:      aMap["id"].(string),
+			Enabled: aMap["enabled"].(bool),
+			Type:    aMap["type"].(string),
+			Schema:  aMap["schema"].(string),
+			Params:  params,
+		})
+	}
+
+	obj, err := vis.ToSavedObject()
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	return obj, nil
+}
+
+func resourceVisualizationRead(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	resp, diagnostics := hc.readObject(ctx, d, &saved_objects.SavedObjectOSD{Type: "visualization", ID: d.Id()})
+	if diagnostics != nil {
+		return diagnostics
+	}
+
+	if resp == nil {
+		// signals the resource must be (re)created
+		d.SetId("")
+		return nil
+	}
+
+	vis, err := visualization.FromSavedObject(resp)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	values := map[string]any{
+		"obj_id":           vis.ID,
+		"title":            vis.Title,
+		"description":      vis.Description,
+		"type":             vis.Type,
+		"saved_search_id":  vis.SavedSearchID,
+		"index_pattern_id": vis.IndexPatternID,
+		"query":            vis.Query,
+		"query_language":   vis.QueryLanguage,
+	}
+	if vis.QueryLanguage == "" {
+		values["query_language"] = visualization.DefaultQueryLanguage
+	}
+
+	for key, v := range map[string]any{"params": vis.Params, "ui_state": vis.UIState, "filters": vis.Filters} {
+		s, err := jsonOrDefault(v, key)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		values[key] = s
+	}
+
+	aggs, diagnostics := flattenAggregations(d, vis.Aggregations)
+	if diagnostics != nil {
+		return diagnostics
+	}
+	values["aggregation"] = aggs
+
+	for key, v := range values {
+		if err := d.Set(key, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	d.SetId(vis.ID)
+
+	return nil
+}
+
+// flattenAggregations converts the aggregations into their schema representation. Parameters that were
+// configured in the params-map before are kept there, everything else ends up in params_json.
+func flattenAggregations(d *schema.ResourceData, aggs []visualization.Aggregation) ([]any, diag.Diagnostics) {
+	prior, _ := d.Get("aggregation").([]any)
+
+	result := make([]any, len(aggs))
+	for i, agg := range aggs {
+		priorParams := map[string]any{}
+		if i < len(prior) {
+			if p, ok := prior[i].(map[string]any); ok && p["id"] == agg.ID {
+				priorParams, _ = p["params"].(map[string]any)
+			}
+		}
+
+		params := map[string]any{}
+		rest := map[string]any{}
+		for k, v := range agg.Params {
+			if s, isString := v.(string); isString && priorParams[k] != nil {
+				params[k] = s
+				continue
+			}
+			rest[k] = v
+		}
+
+		paramsJSON, err := json.Marshal(rest)
+		if err != nil {
+			return nil, diag.Errorf("could not encode params of aggregation %q: %v", agg.ID, err)
+		}
+
+		result[i] = map[string]any{
+			"id":          agg.ID,
+			"type":        agg.Type,
+			"schema":      agg.Schema,
+			"enabled":     agg.Enabled,
+			"params":      params,
+			"params_json": string(paramsJSON),
+		}
+	}
+
+	return result, nil
+}
+
+// jsonOrDefault encodes v as JSON. Missing values result in the default of the attribute, so that an
+// unset attribute does not cause a diff.
+func jsonOrDefault(v any, key string) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("could not encode %s: %w", key, err)
+	}
+	if string(b) == "null" {
+		return resourceVisualization().Schema[key].Default.(string), nil
+	}
+
+	return string(b), nil
+}
+
+func resourceVisualizationWrite(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	req, diagnostics := resourceVisualizationToRequest(d)
+	if diagnostics != nil {
+		return diagnostics
+	}
+
+	diagnostics = hc.writeObject(ctx, d, req)
+	if diagnostics.HasError() {
+		return diagnostics
+	}
+
+	d.SetId(req.ID)
+
+	return diagnostics
+}
+
+func resourceVisualizationDelete(ctx context.Context, d *schema.ResourceData, m any) diag.Diagnostics {
+	hc, isAssertedType := m.(*OpensearchDashboardsClient)
+	if !isAssertedType {
+		return diag.Errorf("unexpected type provided as client: %T", m)
+	}
+
+	return hc.deleteObject(ctx, d, &saved_objects.SavedObjectOSD{Type: "visualization", ID: d.Id()})
+}
+
+// resourceVisualizationCustomizeDiff is shared by all visualization resources, they are saved as type visualization.
+func resourceVisualizationCustomizeDiff(_ context.Context, d *schema.ResourceDiff, m any) error {
+	if hc, ok := m.(*OpensearchDashboardsClient); ok {
+		hc.planCreate(d, "visualization")
+		return hc.checkManagedMarker(d, "visualization")
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package opensearch
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+// addTenantsSchema adds the tenants of the security plugin to the schema of a saved object resource.
+func addTenantsSchema(r *schema.Resource) *schema.Resource {
+	r.Schema["tenants"] = &schema.Schema{
+		Description: "Tenants of the security plugin to replicate the object into, e.g. `global` or the name of a custom tenant. " +
+			"If not set, the object is managed in the tenant of the user. A copy which is missing or differs from the others is reported as drift of its tenant. " +
+			"Copies are deleted from tenants removed from the list, except when the list is removed entirely.",
+		Type:     schema.TypeSet,
+		Optional: true,
+		Elem:     &schema.Schema{Type: schema.TypeString},
+	}
+
+	return r
+}
+
+// tenantsOf returns the configured tenants, or the tenant of the user as empty string if none are configured.
+func tenantsOf(tenants any) []string {
+	set, ok := tenants.(*schema.Set)
+	if !ok || set.Len() == 0 {
+		return []string{""}
+	}
+
+	return expandStringSet(set)
+}
+
+// readObject reads obj from all tenants of the resource and returns the first copy found. Tenants whose copy
+// is missing or differs from the first one are removed from tenants, so they show up in the plan.
+func (c *OpensearchDashboardsClient) readObject(ctx context.Context, d *schema.ResourceData, obj *saved_objects.SavedObjectOSD) (*saved_objects.SavedObjectTF, diag.Diagnostics) {
+	var first *saved_objects.SavedObjectTF
+	var inSync []string
+	for _, tenant := range tenantsOf(d.Get("tenants")) {
+		tenantObj := *obj
+		tenantObj.Tenant = tenant
+		resp, err := c.SavedObjects.GetObject(ctx, &tenantObj)
+		if err != nil && !errors.Is(err, apierror.ErrNotFound) {
+			return nil, errorDiagnostics(err, "could not read %s %q", obj.Type, obj.ID)
+		}
+
+		switch {
+		case resp == nil:
+			tflog.Warn(ctx, "saved object does not exist in tenant", map[string]any{"type": obj.Type, "id": obj.ID, "tenant": tenant})
+		case first == nil:
+			first = resp
+			inSync = append(inSync, tenant)
+		case sameObject(first, resp):
+			inSync = append(inSync, tenant)
+		default:
+			tflog.Warn(ctx, "saved object differs between tenants", map[string]any{"type": obj.Type, "id": obj.ID, "tenant": tenant, "compared_tenant": inSync[0]})
+		}
+	}
+
+	if d.Get("tenants").(*schema.Set).Len() > 0 {
+		if err := d.Set("tenants", inSync); err != nil {
+			return nil, diag.Errorf("could not set tenants: %v", err)
+		}
+	}
+
+	return first, nil
+}
+
+func sameObject(a, b *saved_objects.SavedObjectTF) bool {
+	if !saved_objects.EquivalentAttributes(a.Attributes, b.Attributes) {
+		return false
+	}
+
+	return reflect.DeepEqual(sortedReferences(a.References), sortedReferences(b.References))
+}
+
+func sortedReferences(refs []saved_objects.Reference) []saved_objects.Reference {
+	result := append([]saved_objects.Reference{}, refs...)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Type+"/"+result[i].ID < result[j].Type+"/"+result[j].ID
+	})
+
+	return result
+}
+
+// writeObject writes obj into all tenants of the resource and deletes it from the tenants removed from it.
+func (c *OpensearchDashboardsClient) writeObject(ctx context.Context, d *schema.ResourceData, obj *saved_objects.SavedObjectOSD) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for _, tenant := range tenantsOf(d.Get("tenants")) {
+		tenantObj := *obj
+		tenantObj.Tenant = tenant
+
+		diags = append(diags, c.validateReferencesExist(ctx, &tenantObj)...)
+		if diags.HasError() {
+			return diags
+		}
+
+		diags = append(diags, errorDiagnostics(c.SavedObjects.SaveObject(ctx, &tenantObj), "could not save %s %q", obj.Type, obj.ID)...)
+		if diags.HasError() {
+			return diags
+		}
+	}
+
+	if !d.HasChange("tenants") {
+		return diags
+	}
+
+	oldTenants, newTenants := d.GetChange("tenants")
+	if newTenants.(*schema.Set).Len() == 0 {
+		// the object is managed in the tenant of the user now, the copies in the other tenants are kept
+		return diags
+	}
+	for _, tenant := range expandStringSet(oldTenants.(*schema.Set).Difference(newTenants.(*schema.Set))) {
+		diags = append(diags, c.deleteFromTenant(ctx, d, tenant, obj)...)
+		if diags.HasError() {
+			return diags
+		}
+	}
+
+	return diags
+}
+
+// deleteObject deletes obj from all tenants of the resource.
+func (c *OpensearchDashboardsClient) deleteObject(ctx context.Context, d *schema.ResourceData, obj *saved_objects.SavedObjectOSD) diag.Diagnostics {
+	for _, tenant := range tenantsOf(d.Get("tenants")) {
+		diagnostics := c.deleteFromTenant(ctx, d, tenant, obj)
+		if diagnostics != nil {
+			return diagnostics
+		}
+	}
+
+	return nil
+}
+
+func (c *OpensearchDashboardsClient) deleteFromTenant(ctx context.Context, d *schema.ResourceData, tenant string, obj *saved_objects.SavedObjectOSD) diag.Diagnostics {
+	diagnostics := c.checkInboundReferences(ctx, d, tenant, saved_objects.ObjectKey{Type: obj.Type, ID: obj.ID})
+	if diagnostics != nil {
+		return diagnostics
+	}
+
+	tenantObj := *obj
+	tenantObj.Tenant = tenant
+	if err := c.SavedObjects.DeleteObject(ctx, &tenantObj); err != nil {
+		tflog.Error(ctx, "could not delete saved object", map[string]any{"type": obj.Type, "id": obj.ID, "tenant": tenant, "error": err.Error()})
+
+		return errorDiagnostics(err, "could not delete %s %q", obj.Type, obj.ID)
+	}
+
+	return nil
+}
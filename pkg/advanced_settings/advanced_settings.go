@@ -0,0 +1,123 @@
+package advanced_settings
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/httplog"
+)
+
+type httpPayload struct {
+	Changes map[string]any `json:"changes"`
+}
+
+type httpResponse struct {
+	Settings map[string]setting `json:"settings"`
+}
+
+type setting struct {
+	UserValue any `json:"userValue"`
+}
+
+// Client is the interface of the advanced settings API implemented by Provider.
+type Client interface {
+	GetSettings(ctx context.Context) (map[string]any, error)
+	SetSettings(ctx context.Context, changes map[string]any) error
+}
+
+var _ Client = &Provider{}
+
+type Provider struct {
+	Url        string
+	httpClient *http.Client
+}
+
+func NewProvider(baseUrl string, client *http.Client) *Provider {
+	return &Provider{
+		Url:        fmt.Sprintf("%s/api/opensearch-dashboards/settings", baseUrl),
+		httpClient: client,
+	}
+}
+
+// GetSettings returns all settings which were changed by a user. Settings which still have their
+// default value are not returned by the API.
+func (p *Provider) GetSettings(ctx context.Context) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(httplog.WithAttempts(ctx), http.MethodGet, p.Url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not build request to GET %v %w", p.Url, err)
+	}
+	req.Header.Set("osd-xsrf", "true")
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET '%v' failed: %w", req.URL.String(), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, apierror.FromResponse(res)
+	}
+
+	result := &httpResponse{}
+	err = json.NewDecoder(res.Body).Decode(result)
+	if err != nil {
+		return nil, fmt.Errorf("request failed, cannot decode response body, err %w ", err)
+	}
+
+	settings := make(map[string]any, len(result.Settings))
+	for key, s := range result.Settings {
+		if s.UserValue != nil {
+			settings[key] = s.UserValue
+		}
+	}
+
+	return settings, nil
+}
+
+// SetSettings changes the given settings. A nil value resets the setting to its default.
+func (p *Provider) SetSettings(ctx context.Context, changes map[string]any) error {
+	requestBody := httpPayload{Changes: changes}
+	jsonBytes, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("failed to encode settings as JSON: %+v \n%w", requestBody, err)
+	}
+	req, err := http.NewRequestWithContext(httplog.WithAttempts(ctx), http.MethodPost, p.Url, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return fmt.Errorf("could not create request for %v %v: %w", http.MethodPost, p.Url, err)
+	}
+
+	req.Header.Set("osd-xsrf", "true")
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST '%s' failed: %w", req.URL.String(), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return apierror.FromResponse(res)
+	}
+	return nil
+}
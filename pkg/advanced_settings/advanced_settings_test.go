@@ -0,0 +1,100 @@
+package advanced_settings
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetSettings(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		wantErr     bool
+		want        map[string]any
+		handlerFunc http.HandlerFunc
+	}{
+		{
+			desc: "must only return settings with user values",
+			want: map[string]any{"dateFormat:tz": "Europe/Berlin", "discover:sampleSize": float64(100)},
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(`{"settings":{"buildNum":{"readonly":true},"dateFormat:tz":{"userValue":"Europe/Berlin"},"discover:sampleSize":{"userValue":100}}}`))
+			},
+		},
+		{
+			desc:    "must fail when HTTP status not 200 OK",
+			wantErr: true,
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			handler := http.NewServeMux()
+			handler.HandleFunc("/_dashboards/api/opensearch-dashboards/settings", tC.handlerFunc)
+
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			provider := NewProvider(srv.URL+"/_dashboards", http.DefaultClient)
+			settings, err := provider.GetSettings(context.TODO())
+			if tC.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(settings, tC.want) {
+				t.Errorf("expected %+v but got %+v", tC.want, settings)
+			}
+		})
+	}
+}
+
+func TestSetSettings(t *testing.T) {
+	var received map[string]any
+	handler := http.NewServeMux()
+	handler.HandleFunc("/_dashboards/api/opensearch-dashboards/settings", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("osd-xsrf") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	provider := NewProvider(srv.URL+"/_dashboards", http.DefaultClient)
+	err := provider.SetSettings(context.TODO(), map[string]any{"theme:darkMode": true, "defaultRoute": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"changes": map[string]any{"theme:darkMode": true, "defaultRoute": nil}}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("expected body %+v but got %+v", want, received)
+	}
+}
@@ -0,0 +1,57 @@
+package advanced_settings
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"testing"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/cassette"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/cassettetest"
+)
+
+func TestCassetteAdvancedSettings(t *testing.T) {
+	for _, version := range cassette.Versions {
+		t.Run(version, func(t *testing.T) {
+			baseURL, client := cassettetest.Start(t, version, "advanced_settings", cassette.Options{})
+			p := NewProvider(baseURL, client)
+			ctx := context.Background()
+
+			if err := p.SetSettings(ctx, map[string]any{"dateFormat:tz": "UTC", "discover:sampleSize": 100}); err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.GetSettings(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got["dateFormat:tz"] != "UTC" || got["discover:sampleSize"] != float64(100) {
+				t.Errorf("expected the changed settings but got %v", got)
+			}
+
+			if err := p.SetSettings(ctx, map[string]any{"dateFormat:tz": nil, "discover:sampleSize": nil}); err != nil {
+				t.Fatal(err)
+			}
+			got, err = p.GetSettings(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := got["dateFormat:tz"]; ok {
+				t.Errorf("expected the setting to be reset but got %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Package apierror contains the errors returned by the clients of the OpenSearch Dashboards API.
+package apierror
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrNotFound is matched by errors.Is for every APIError with status 404 Not Found.
+var ErrNotFound = errors.New("not found")
+
+// APIError is returned if OpenSearch Dashboards responds with an unexpected status.
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Body is the response body
+	Body string
+}
+
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s '%s' failed with status %d", e.Method, e.URL, e.StatusCode)
+	}
+	return fmt.Sprintf("%s '%s' failed with status %d\nresponse_body: %s", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// ConflictError is returned for status 409 Conflict, e.g. if an object already exists or was modified concurrently.
+type ConflictError struct {
+	APIError
+}
+
+func (e *ConflictError) Unwrap() error {
+	return &e.APIError
+}
+
+// FromResponse returns the error for a response with an unexpected status. The body of the response is read.
+func FromResponse(res *http.Response) error {
+	apiErr := APIError{StatusCode: res.StatusCode}
+	if res.Request != nil {
+		apiErr.Method = res.Request.Method
+		apiErr.URL = res.Request.URL.String()
+	}
+	if body, err := io.ReadAll(res.Body); err == nil {
+		apiErr.Body = string(body)
+	}
+
+	if res.StatusCode == http.StatusConflict {
+		return &ConflictError{APIError: apiErr}
+	}
+	return &apiErr
+}
+
+// FromStatus returns the error for an object of a bulk response with the status, e.g. of _bulk_get.
+func FromStatus(method, url string, statusCode int, message string) error {
+	apiErr := APIError{Method: method, URL: url, StatusCode: statusCode, Body: message}
+	if statusCode == http.StatusConflict {
+		return &ConflictError{APIError: apiErr}
+	}
+	return &apiErr
+}
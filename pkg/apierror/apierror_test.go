@@ -0,0 +1,62 @@
+package apierror
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFromResponse(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/api/saved_objects/search/mock-search", nil)
+	testCases := []struct {
+		desc         string
+		status       int
+		wantNotFound bool
+		wantConflict bool
+	}{
+		{desc: "must match ErrNotFound for 404", status: http.StatusNotFound, wantNotFound: true},
+		{desc: "must return ConflictError for 409", status: http.StatusConflict, wantConflict: true},
+		{desc: "must return APIError for other status", status: http.StatusInternalServerError},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			res := &http.Response{StatusCode: tC.status, Request: req, Body: io.NopCloser(strings.NewReader(`{"message":"boom"}`))}
+			err := fmt.Errorf("wrapped: %w", FromResponse(res))
+
+			if got := errors.Is(err, ErrNotFound); got != tC.wantNotFound {
+				t.Errorf("expected errors.Is(err, ErrNotFound) to be %v", tC.wantNotFound)
+			}
+			var conflictErr *ConflictError
+			if got := errors.As(err, &conflictErr); got != tC.wantConflict {
+				t.Errorf("expected errors.As(err, *ConflictError) to be %v", tC.wantConflict)
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected APIError but got %T", err)
+			}
+			if apiErr.StatusCode != tC.status || apiErr.Body != `{"message":"boom"}` || apiErr.Method != http.MethodGet {
+				t.Errorf("unexpected APIError %+v", apiErr)
+			}
+		})
+	}
+}
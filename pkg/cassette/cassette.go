@@ -0,0 +1,341 @@
+// Package cassette records the HTTP interactions of tests with OpenSearch Dashboards in fixture files, so called
+// cassettes, and replays them offline. The API clients are tested against the responses of every version in Versions
+// without running it, tests start their cassettes with package cassettetest.
+//
+// Tests replay their cassettes by default. With OS_CASSETTE_MODE=record and OS_CASSETTE_VERSION set to one of
+// Versions, the tests of that version send their requests to OS_CASSETTE_BASE_URL instead, by default the
+// smoketest containers on http://localhost:5601, and overwrite their cassettes with the interactions.
+package cassette
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/httplog"
+)
+
+const (
+	ModeEnv    = "OS_CASSETTE_MODE"
+	VersionEnv = "OS_CASSETTE_VERSION"
+	BaseURLEnv = "OS_CASSETTE_BASE_URL"
+)
+
+// Versions are the versions of OpenSearch Dashboards with cassettes, each in its own directory. A version is only
+// added together with cassettes recorded from it.
+var Versions = []string{"1.3"}
+
+type Mode int
+
+const (
+	// ModeReplay serves the requests from the cassette and fails requests without matching interaction.
+	ModeReplay Mode = iota
+	// ModeRecord sends the requests and records them in the cassette.
+	ModeRecord
+)
+
+// matchedHeaders are recorded and must be equal for a request to match. All other headers are neither recorded
+// nor matched, so no credentials end up in cassettes.
+var matchedHeaders = []string{"osd-xsrf", "securitytenant"}
+
+// Cassette is the content of a fixture file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request and the response to it.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string `json:"method"`
+	// Path is the path and query relative to the base URL
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is compared as JSON, so its formatting and the order of keys do not matter
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type Options struct {
+	// BaseURL is the URL the recorded paths are relative to
+	BaseURL string
+	// SensitivePaths are dotted paths of JSON attributes which are redacted in recorded bodies, see httplog.Options
+	SensitivePaths []string
+}
+
+// Transport records or replays requests. In replay mode every interaction is used once, in the order of the
+// cassette, so requests which are sent repeatedly can get different responses.
+type Transport struct {
+	mode     Mode
+	path     string
+	base     *url.URL
+	next     http.RoundTripper
+	redactor *httplog.Redactor
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New returns a Transport for the cassette file. In replay mode the file must exist, in record mode it is written
+// by Save. Requests are recorded with the succeeding middleware next, the http.DefaultTransport will be used if nil.
+func New(path string, mode Mode, next http.RoundTripper, opts Options) (*Transport, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	base, err := url.Parse(opts.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %s: %w", opts.BaseURL, err)
+	}
+
+	t := &Transport{mode: mode, path: path, base: base, next: next, redactor: httplog.NewRedactor(opts.SensitivePaths)}
+	if mode == ModeRecord {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &t.cassette); err != nil {
+		return nil, fmt.Errorf("cassette %s is not valid: %w", path, err)
+	}
+	t.used = make([]bool, len(t.cassette.Interactions))
+
+	return t, nil
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := t.request(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.mode == ModeRecord {
+		return t.record(req, recorded)
+	}
+
+	// the bodies in the cassette are redacted, so the request has to be redacted the same way to match
+	if len(recorded.Body) > 0 {
+		recorded.Body = json.RawMessage(t.redactor.Body(recorded.Body))
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, interaction := range t.cassette.Interactions {
+		if !t.used[i] && matches(interaction.Request, recorded) {
+			t.used[i] = true
+			return interaction.Response.toHTTP(req), nil
+		}
+	}
+
+	return nil, fmt.Errorf("cassette %s has no unused interaction for %s %s", t.path, recorded.Method, recorded.Path)
+}
+
+func (t *Transport) record(req *http.Request, recorded Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		//nolint: wrapcheck
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not read response to record: %w", err)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	response := Response{Status: res.StatusCode}
+	if contentType := res.Header.Get("Content-Type"); contentType != "" {
+		response.Headers = map[string]string{"Content-Type": contentType}
+	}
+	response.Body, err = t.body(body)
+	if err != nil {
+		return nil, err
+	}
+	recorded.Body, err = t.body(recorded.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{Request: recorded, Response: response})
+	t.mu.Unlock()
+
+	return res, nil
+}
+
+// request returns the request as it is recorded, with the body unredacted.
+func (t *Transport) request(req *http.Request) (Request, error) {
+	result := Request{
+		Method: req.Method,
+		Path:   strings.TrimPrefix(req.URL.RequestURI(), strings.TrimSuffix(t.base.Path, "/")),
+	}
+	for _, name := range matchedHeaders {
+		if value := req.Header.Get(name); value != "" {
+			if result.Headers == nil {
+				result.Headers = map[string]string{}
+			}
+			result.Headers[name] = value
+		}
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return Request{}, fmt.Errorf("could not read request body: %w", err)
+		}
+		defer body.Close()
+		if result.Body, err = io.ReadAll(body); err != nil {
+			return Request{}, fmt.Errorf("could not read request body: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// body returns the redacted body to record.
+func (t *Transport) body(data []byte) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	redacted := t.redactor.Body(data)
+	if !json.Valid([]byte(redacted)) {
+		return nil, fmt.Errorf("cannot record body which is not JSON: %.100s", data)
+	}
+
+	return json.RawMessage(redacted), nil
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing in replay mode.
+func (t *Transport) Save() error {
+	if t.mode != ModeRecord {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(t.cassette); err != nil {
+		return fmt.Errorf("could not encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return fmt.Errorf("could not create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(t.path, b.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("could not write cassette: %w", err)
+	}
+
+	return nil
+}
+
+// Unused returns the interactions which were not replayed yet.
+func (t *Transport) Unused() []Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var result []Interaction
+	for i, interaction := range t.cassette.Interactions {
+		if !t.used[i] {
+			result = append(result, interaction)
+		}
+	}
+
+	return result
+}
+
+// matches reports whether the request matches the recorded one. The query parameters and the JSON body are
+// compared independent of their order and formatting.
+func matches(recorded, req Request) bool {
+	if recorded.Method != req.Method || !reflect.DeepEqual(recorded.Headers, req.Headers) {
+		return false
+	}
+	recordedURL, err := url.Parse(recorded.Path)
+	if err != nil {
+		return false
+	}
+	reqURL, err := url.Parse(req.Path)
+	if err != nil || recordedURL.Path != reqURL.Path || !reflect.DeepEqual(recordedURL.Query(), reqURL.Query()) {
+		return false
+	}
+	if len(recorded.Body) == 0 || len(req.Body) == 0 {
+		return len(recorded.Body) == len(req.Body)
+	}
+
+	var recordedBody, reqBody any
+	if json.Unmarshal(recorded.Body, &recordedBody) != nil || json.Unmarshal(req.Body, &reqBody) != nil {
+		return bytes.Equal(recorded.Body, req.Body)
+	}
+
+	return reflect.DeepEqual(recordedBody, reqBody)
+}
+
+func (r Response) toHTTP(req *http.Request) *http.Response {
+	header := http.Header{}
+	for name, value := range r.Headers {
+		header.Set(name, value)
+	}
+	// cassettes are indented for readability, the API responds with compact JSON
+	var body bytes.Buffer
+	if err := json.Compact(&body, r.Body); err != nil {
+		body.Reset()
+		body.Write(r.Body)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body.Bytes())),
+		ContentLength: int64(body.Len()),
+		Request:       req,
+	}
+}
+
+// ModeFromEnv returns the mode set with OS_CASSETTE_MODE, replay by default.
+func ModeFromEnv() (Mode, error) {
+	switch mode := os.Getenv(ModeEnv); mode {
+	case "", "replay":
+		return ModeReplay, nil
+	case "record":
+		return ModeRecord, nil
+	default:
+		return ModeReplay, fmt.Errorf("%s must be record or replay but is %s", ModeEnv, mode)
+	}
+}
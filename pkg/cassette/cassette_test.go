@@ -0,0 +1,192 @@
+package cassette
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testCassette = `{"interactions":[
+	{
+		"request":{"method":"POST","path":"/api/saved_objects/_bulk_get","headers":{"osd-xsrf":"true"},"body":[{"type":"dashboard","id":"a"}]},
+		"response":{"status":200,"headers":{"Content-Type":"application/json"},"body":{"saved_objects":[{"id":"a"}]}}
+	},
+	{
+		"request":{"method":"GET","path":"/api/saved_objects/_find?type=dashboard&page=1","headers":{"osd-xsrf":"true","securitytenant":"global"}},
+		"response":{"status":200,"body":{"page":1}}
+	},
+	{
+		"request":{"method":"GET","path":"/api/saved_objects/_find?type=dashboard&page=1","headers":{"osd-xsrf":"true","securitytenant":"global"}},
+		"response":{"status":200,"body":{"page":2}}
+	}
+]}`
+
+func TestReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := os.WriteFile(path, []byte(testCassette), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	transport, err := New(path, ModeReplay, nil, Options{BaseURL: "http://osd.test/_dashboards"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: transport}
+
+	testCases := []struct {
+		desc    string
+		method  string
+		path    string
+		tenant  string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{
+			desc:    "must not match other tenants",
+			method:  http.MethodGet,
+			path:    "/api/saved_objects/_find?page=1&type=dashboard",
+			wantErr: true,
+		},
+		{
+			desc:   "must match query parameters in any order",
+			method: http.MethodGet,
+			path:   "/api/saved_objects/_find?page=1&type=dashboard",
+			tenant: "global",
+			want:   `{"page":1}`,
+		},
+		{
+			desc:   "must replay the next interaction for a repeated request",
+			method: http.MethodGet,
+			path:   "/api/saved_objects/_find?type=dashboard&page=1",
+			tenant: "global",
+			want:   `{"page":2}`,
+		},
+		{
+			desc:    "must not replay an interaction twice",
+			method:  http.MethodGet,
+			path:    "/api/saved_objects/_find?type=dashboard&page=1",
+			tenant:  "global",
+			wantErr: true,
+		},
+		{
+			desc:    "must not match other bodies",
+			method:  http.MethodPost,
+			path:    "/api/saved_objects/_bulk_get",
+			body:    `[{"type":"dashboard","id":"b"}]`,
+			wantErr: true,
+		},
+		{
+			desc:   "must match JSON bodies independent of formatting",
+			method: http.MethodPost,
+			path:   "/api/saved_objects/_bulk_get",
+			body:   `[ {"id": "a", "type": "dashboard"} ]`,
+			want:   `{"saved_objects":[{"id":"a"}]}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var body io.Reader
+			if tC.body != "" {
+				body = strings.NewReader(tC.body)
+			}
+			req, _ := http.NewRequest(tC.method, "http://osd.test/_dashboards"+tC.path, body)
+			req.Header.Set("osd-xsrf", "true")
+			if tC.tenant != "" {
+				req.Header.Set("securitytenant", tC.tenant)
+			}
+
+			res, err := client.Do(req)
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("expected error %v but got %v", tC.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			defer res.Body.Close()
+			got, _ := io.ReadAll(res.Body)
+			if string(got) != tC.want {
+				t.Errorf("expected %s but got %s", tC.want, got)
+			}
+		})
+	}
+
+	if unused := transport.Unused(); len(unused) != 0 {
+		t.Errorf("expected all interactions to be used but got %v", unused)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_dashboards/api/saved_objects/dashboard/a" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "security_authentication=secret-cookie")
+		w.Write([]byte(`{"id":"a","attributes":{"title":"a","description":"secret-description"}}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "1.3", "cassette.json")
+	opts := Options{BaseURL: srv.URL + "/_dashboards", SensitivePaths: []string{"attributes.description"}}
+	transport, err := New(path, ModeRecord, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(client *http.Client) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, opts.BaseURL+"/api/saved_objects/dashboard/a?overwrite=true",
+			strings.NewReader(`{"attributes":{"description":"secret-request"}}`))
+		req.Header.Set("osd-xsrf", "true")
+		req.Header.Set("Authorization", "Basic secret-password")
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return string(body)
+	}
+
+	if got := send(&http.Client{Transport: transport}); !strings.Contains(got, "secret-description") {
+		t.Errorf("expected the response to be passed on unchanged but got %s", got)
+	}
+	if err := transport.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded, _ := os.ReadFile(path)
+	if strings.Contains(string(recorded), "secret") || strings.Contains(string(recorded), srv.URL) {
+		t.Errorf("expected credentials, sensitive attributes and the host to be removed but got %s", recorded)
+	}
+
+	replay, err := New(path, ModeReplay, nil, Options{BaseURL: opts.BaseURL, SensitivePaths: opts.SensitivePaths})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"attributes":{"description":"***","title":"a"},"id":"a"}`
+	if got := send(&http.Client{Transport: replay}); got != want {
+		t.Errorf("expected the recorded response %s but got %s", want, got)
+	}
+}
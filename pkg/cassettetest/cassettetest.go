@@ -0,0 +1,72 @@
+// Package cassettetest starts the cassettes of package cassette in tests.
+package cassettetest
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/cassette"
+)
+
+const (
+	defaultRecordBaseURL = "http://localhost:5601"
+	// replayBaseURL is the base URL of the clients in replay mode, no request leaves the process
+	replayBaseURL = "http://opensearch-dashboards.test"
+)
+
+// Start returns the base URL and the HTTP client for a test using the cassette name of the version, located in
+// testdata/cassettes. In replay mode the test fails if it did not use all interactions, in record mode the
+// cassette is written when the test ends. Tests of other versions than OS_CASSETTE_VERSION are skipped while
+// recording.
+func Start(t testing.TB, version, name string, opts cassette.Options) (string, *http.Client) {
+	t.Helper()
+	mode, err := cassette.ModeFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.BaseURL = replayBaseURL
+	if mode == cassette.ModeRecord {
+		if recording := os.Getenv(cassette.VersionEnv); recording != version {
+			t.Skipf("recording cassettes of version %q", recording)
+		}
+		opts.BaseURL = defaultRecordBaseURL
+		if baseURL := os.Getenv(cassette.BaseURLEnv); baseURL != "" {
+			opts.BaseURL = strings.TrimSuffix(baseURL, "/")
+		}
+	}
+
+	transport, err := cassette.New(filepath.Join("testdata", "cassettes", version, name+".json"), mode, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := transport.Save(); err != nil {
+			t.Error(err)
+		}
+		if unused := transport.Unused(); len(unused) > 0 && !t.Failed() {
+			t.Errorf("%d interactions of the cassette were not used, the first one is %s %s", len(unused), unused[0].Request.Method, unused[0].Request.Path)
+		}
+	})
+
+	return opts.BaseURL, &http.Client{Transport: transport}
+}
@@ -0,0 +1,58 @@
+package default_index_pattern
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"testing"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/cassette"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/cassettetest"
+)
+
+func TestCassetteDefaultIndexPattern(t *testing.T) {
+	for _, version := range cassette.Versions {
+		t.Run(version, func(t *testing.T) {
+			baseURL, client := cassettetest.Start(t, version, "default_index_pattern", cassette.Options{})
+			p := NewProvider(baseURL, client)
+			ctx := context.Background()
+
+			id := "cassette-pattern"
+			if err := p.SetDefaultIndexPattern(ctx, &id); err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.GetDefaultIndexPattern(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == nil || got.IndexPatternId == nil || *got.IndexPatternId != id {
+				t.Errorf("expected default index pattern %s but got %v", id, got)
+			}
+
+			if err := p.SetDefaultIndexPattern(ctx, nil); err != nil {
+				t.Fatal(err)
+			}
+			got, err = p.GetDefaultIndexPattern(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != nil {
+				t.Errorf("expected no default index pattern but got %v", *got.IndexPatternId)
+			}
+		})
+	}
+}
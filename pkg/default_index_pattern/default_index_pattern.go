@@ -5,10 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/httplog"
 )
 
 type OpenSearchRequestBody struct {
@@ -23,6 +23,22 @@ type httpPayloadChanges struct {
 	DefaultIndex *string `json:"defaultIndex"`
 }
 
+type httpResponse struct {
+	Settings struct {
+		DefaultIndex *struct {
+			UserValue *string `json:"userValue"`
+		} `json:"defaultIndex"`
+	} `json:"settings"`
+}
+
+// Client is the interface of the default index pattern setting implemented by Provider.
+type Client interface {
+	GetDefaultIndexPattern(ctx context.Context) (*OpenSearchRequestBody, error)
+	SetDefaultIndexPattern(ctx context.Context, indexPatternId *string) error
+}
+
+var _ Client = &Provider{}
+
 type Provider struct {
 	Url                    string
 	httpClient             *http.Client
@@ -36,49 +52,52 @@ func NewProvider(baseUrl string, client *http.Client) *Provider {
 	}
 }
 
-func (p *Provider) GetDefaultIndexPattern(ctx context.Context) (*OpenSearchRequestBody, diag.Diagnostics) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Url, nil)
-	req.Header.Set("osd-xsrf", "true")
-	req.Header.Set("Content-Type", "application/json")
-
+// GetDefaultIndexPattern returns the default index pattern. If no default index pattern is set, nil is returned.
+func (p *Provider) GetDefaultIndexPattern(ctx context.Context) (*OpenSearchRequestBody, error) {
+	req, err := http.NewRequestWithContext(httplog.WithAttempts(ctx), http.MethodGet, p.Url, nil)
 	if err != nil {
-		return nil, diag.FromErr(fmt.Errorf("could not build request to GET %v %w", p.Url, err))
+		return nil, fmt.Errorf("could not build request to GET %v %w", p.Url, err)
 	}
+	req.Header.Set("osd-xsrf", "true")
+	req.Header.Set("Content-Type", "application/json")
 
 	res, err := p.httpClient.Do(req)
 	if err != nil {
-		return nil, diag.FromErr(fmt.Errorf("GET '%v' failed: %w", req.URL.String(), err))
+		return nil, fmt.Errorf("GET '%v' failed: %w", req.URL.String(), err)
 	}
+	defer res.Body.Close()
+
 	if res.StatusCode == http.StatusNotFound {
 		return nil, nil
 	}
 	if res.StatusCode != http.StatusOK {
-		response, bodyReadErr := io.ReadAll(res.Body)
-		if bodyReadErr != nil {
-			return nil, diag.FromErr(fmt.Errorf("GET '%v' failed with status %d", req.URL.String(), res.StatusCode))
-		}
-		return nil, diag.FromErr(fmt.Errorf("GET '%v' failed with status %d\nresponse_body: %v", req.URL.String(), res.StatusCode, string(response)))
+		return nil, apierror.FromResponse(res)
 	}
 
-	result := &httpPayload{}
+	result := &httpResponse{}
 	err = json.NewDecoder(res.Body).Decode(result)
 	if err != nil {
-		return nil, diag.FromErr(fmt.Errorf("request failed, cannot decode response body, err %w ", err))
+		return nil, fmt.Errorf("request failed, cannot decode response body, err %w ", err)
 	}
 
-	return &OpenSearchRequestBody{IndexPatternId: result.Changes.DefaultIndex}, nil
+	defaultIndex := result.Settings.DefaultIndex
+	if defaultIndex == nil || defaultIndex.UserValue == nil || *defaultIndex.UserValue == "" {
+		return nil, nil
+	}
+
+	return &OpenSearchRequestBody{IndexPatternId: defaultIndex.UserValue}, nil
 }
 
-func (p *Provider) SetDefaultIndexPattern(ctx context.Context, indexPatternId *string) diag.Diagnostics {
+func (p *Provider) SetDefaultIndexPattern(ctx context.Context, indexPatternId *string) error {
 	requestBody := httpPayload{Changes: httpPayloadChanges{DefaultIndex: indexPatternId}}
 	jsonBytes, err := json.Marshal(requestBody)
 	if err != nil {
-		return diag.Errorf("failed to encode index pattern as JSON: %+v \n%v", requestBody, err)
+		return fmt.Errorf("failed to encode index pattern as JSON: %+v \n%w", requestBody, err)
 	}
 	payload := bytes.NewBuffer(jsonBytes)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Url, payload)
+	req, err := http.NewRequestWithContext(httplog.WithAttempts(ctx), http.MethodPost, p.Url, payload)
 	if err != nil {
-		return diag.FromErr(err)
+		return fmt.Errorf("could not create request for %v %v: %w", http.MethodPost, p.Url, err)
 	}
 
 	req.Header.Set("osd-xsrf", "true")
@@ -86,14 +105,12 @@ func (p *Provider) SetDefaultIndexPattern(ctx context.Context, indexPatternId *s
 
 	res, err := p.httpClient.Do(req)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("POST '%s' failed, err %s, statuscode %d, \nrequest_body: %s", req.URL.String(), err, res.StatusCode, string(jsonBytes)))
+		return fmt.Errorf("POST '%s' failed: %w", req.URL.String(), err)
 	}
+	defer res.Body.Close()
+
 	if res.StatusCode != http.StatusOK {
-		rawBody, bodyReadErr := io.ReadAll(res.Body)
-		if bodyReadErr != nil {
-			return diag.FromErr(fmt.Errorf("POST '%s' failed with status %d\nrequest_body: %s", req.URL.String(), err, string(jsonBytes)))
-		}
-		return diag.FromErr(fmt.Errorf("POST '%s' failed with status %d\nrequest_body: %s\nresponse_body: %s", req.URL.String(), res.StatusCode, string(jsonBytes), string(rawBody)))
+		return apierror.FromResponse(res)
 	}
 	return nil
 }
@@ -0,0 +1,99 @@
+package default_index_pattern
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetDefaultIndexPattern(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		wantErr     bool
+		want        *string
+		handlerFunc http.HandlerFunc
+	}{
+		{
+			desc: "must get default index pattern",
+			want: func() *string { s := "mock-pattern"; return &s }(),
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(`{"settings":{"buildNum":{"userValue":1},"defaultIndex":{"userValue":"mock-pattern"}}}`))
+			},
+		},
+		{
+			desc: "must return nil if no default index pattern is set",
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(`{"settings":{"buildNum":{"userValue":1}}}`))
+			},
+		},
+		{
+			desc: "must return nil if the settings do not exist",
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+		},
+		{
+			desc:    "must fail when HTTP status not 200 OK",
+			wantErr: true,
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			handler := http.NewServeMux()
+			handler.HandleFunc("/_dashboards/api/opensearch-dashboards/settings", tC.handlerFunc)
+
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			provider := NewProvider(srv.URL+"/_dashboards", http.DefaultClient)
+			resp, err := provider.GetDefaultIndexPattern(context.TODO())
+			if tC.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			switch {
+			case tC.want == nil && resp != nil:
+				t.Errorf("expected no default index pattern but got %v", *resp.IndexPatternId)
+			case tC.want != nil && (resp == nil || *resp.IndexPatternId != *tC.want):
+				t.Errorf("expected default index pattern %v but got %+v", *tC.want, resp)
+			}
+		})
+	}
+}
+
+func TestSetDefaultIndexPatternTransportError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	provider := NewProvider(srv.URL+"/_dashboards", http.DefaultClient)
+	pattern := "mock-pattern"
+	if err := provider.SetDefaultIndexPattern(context.TODO(), &pattern); err == nil {
+		t.Error("expected error but got none")
+	}
+}
@@ -0,0 +1,360 @@
+// Package fakeosd is an in-memory fake of the OpenSearch Dashboards API, so the API clients and the provider
+// can be tested without containers. It implements the saved objects API with _find, _bulk_get, _bulk_create,
+// _import and _export, the settings, the status and the tenants of the security plugin, selected with the
+// securitytenant header. Write requests without osd-xsrf header are rejected like by the real server.
+package fakeosd
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Profile describes the behavior of a version of OpenSearch Dashboards.
+type Profile struct {
+	// Version is reported by /api/status
+	Version     string
+	BuildNumber int
+	// Types are the saved object types the version supports, requests for other types fail with status code 400
+	Types []string
+	// MigrationVersions are reported with the saved objects of the types
+	MigrationVersions map[string]string
+}
+
+var (
+	// Profile1 behaves like OpenSearch Dashboards 1.3.
+	Profile1 = Profile{
+		Version:     "1.3.6",
+		BuildNumber: 4970,
+		Types:       []string{"config", "dashboard", "index-pattern", "query", "search", "url", "visualization"},
+		MigrationVersions: map[string]string{
+			"dashboard":     "7.9.3",
+			"index-pattern": "7.6.0",
+			"search":        "7.9.3",
+			"visualization": "7.10.0",
+		},
+	}
+	// Profile2 behaves like OpenSearch Dashboards 2.x, which added the types of VisBuilder and of augmented
+	// visualizations.
+	Profile2 = Profile{
+		Version:     "2.11.0",
+		BuildNumber: 7052,
+		Types:       []string{"augment-vis", "config", "dashboard", "index-pattern", "query", "search", "url", "visualization", "visualization-visbuilder"},
+		MigrationVersions: map[string]string{
+			"dashboard":     "7.9.3",
+			"index-pattern": "7.6.0",
+			"search":        "7.9.3",
+			"visualization": "7.10.0",
+		},
+	}
+)
+
+type Options struct {
+	// Profile is the version to behave like, Profile2 if empty
+	Profile Profile
+	// BasePath is prepended to all paths, e.g. /_dashboards like on AWS
+	BasePath string
+}
+
+// Object is a saved object as stored and returned by the server.
+type Object struct {
+	Type             string            `json:"type"`
+	ID               string            `json:"id"`
+	Attributes       map[string]any    `json:"attributes"`
+	References       []Reference       `json:"references"`
+	MigrationVersion map[string]string `json:"migrationVersion,omitempty"`
+	UpdatedAt        string            `json:"updated_at,omitempty"`
+	Version          string            `json:"version,omitempty"`
+}
+
+type Reference struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type key struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// tenant holds the saved objects and the settings of a tenant, the settings are a saved object in the tenant as well.
+type tenant struct {
+	objects  map[key]Object
+	settings map[string]any
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	// Path is relative to the base path, without query
+	Path   string
+	Tenant string
+}
+
+// Server is a running fake. Its state can be inspected and changed with Get, Put and Delete, e.g. to simulate
+// changes outside of Terraform.
+type Server struct {
+	// URL is the base URL of the API, including the base path
+	URL string
+
+	profile  Profile
+	basePath string
+	server   *httptest.Server
+	handler  http.Handler
+
+	mu       sync.Mutex
+	tenants  map[string]*tenant
+	sequence int
+	faults   []*Fault
+	requests []Request
+}
+
+// New starts a fake server. It has to be stopped with Close.
+func New(opts Options) *Server {
+	if opts.Profile.Version == "" {
+		opts.Profile = Profile2
+	}
+	basePath := strings.TrimSuffix(opts.BasePath, "/")
+
+	s := &Server{profile: opts.Profile, basePath: basePath, tenants: map[string]*tenant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", s.status)
+	mux.HandleFunc("GET /api/opensearch-dashboards/settings", s.getSettings)
+	mux.HandleFunc("POST /api/opensearch-dashboards/settings", s.setSettings)
+	mux.HandleFunc("GET /api/saved_objects/_find", s.find)
+	mux.HandleFunc("POST /api/saved_objects/_bulk_get", s.bulkGet)
+	mux.HandleFunc("POST /api/saved_objects/_bulk_create", s.bulkCreate)
+	mux.HandleFunc("POST /api/saved_objects/_export", s.exportObjects)
+	mux.HandleFunc("POST /api/saved_objects/_import", s.importObjects)
+	mux.HandleFunc("GET /api/saved_objects/{type}/{id}", s.getObject)
+	mux.HandleFunc("POST /api/saved_objects/{type}/{id}", s.createObject)
+	mux.HandleFunc("PUT /api/saved_objects/{type}/{id}", s.updateObject)
+	mux.HandleFunc("DELETE /api/saved_objects/{type}/{id}", s.deleteObject)
+	s.handler = http.StripPrefix(basePath, mux)
+
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL + basePath
+
+	return s
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, s.basePath)
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: path, Tenant: tenantOf(r)})
+	fault := s.fault(r.Method, path)
+	s.mu.Unlock()
+
+	if fault != nil && fault.apply(w, r) {
+		return
+	}
+
+	// the header protects against cross site request forgery, the server accepts osd-version instead as well
+	if r.Method != http.MethodGet && r.Header.Get("osd-xsrf") == "" && r.Header.Get("osd-version") == "" {
+		writeError(w, http.StatusBadRequest, "Request must contain a osd-xsrf header.")
+		return
+	}
+
+	s.handler.ServeHTTP(w, r)
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Get returns the object from the tenant, the tenant of the user if empty.
+func (s *Server) Get(tenantName, objType, id string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.tenant(tenantName).objects[key{objType, id}]
+
+	return clone(obj), ok
+}
+
+// Put creates or replaces the object in the tenant, the tenant of the user if empty.
+func (s *Server) Put(tenantName string, obj Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(s.tenant(tenantName), clone(obj))
+}
+
+// Delete deletes the object from the tenant, the tenant of the user if empty.
+func (s *Server) Delete(tenantName, objType, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tenant(tenantName).objects, key{objType, id})
+}
+
+// Settings returns the settings of the tenant which were changed by a user.
+func (s *Server) Settings(tenantName string) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := map[string]any{}
+	for name, value := range s.tenant(tenantName).settings {
+		result[name] = value
+	}
+
+	return result
+}
+
+// SetSetting changes a setting of the tenant, a nil value resets it to its default.
+func (s *Server) SetSetting(tenantName, name string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value == nil {
+		delete(s.tenant(tenantName).settings, name)
+		return
+	}
+	s.tenant(tenantName).settings[name] = value
+}
+
+// tenantOf returns the tenant selected by the request. Without header the tenant stored in the session is used,
+// which is the private tenant of the user for the fake.
+func tenantOf(r *http.Request) string {
+	return r.Header.Get("securitytenant")
+}
+
+// tenant returns the tenant, the caller has to hold the lock.
+func (s *Server) tenant(name string) *tenant {
+	t, ok := s.tenants[name]
+	if !ok {
+		t = &tenant{objects: map[key]Object{}, settings: map[string]any{}}
+		s.tenants[name] = t
+	}
+
+	return t
+}
+
+// put stores the object with a new version, the caller has to hold the lock.
+func (s *Server) put(t *tenant, obj Object) Object {
+	s.sequence++
+	if obj.Attributes == nil {
+		obj.Attributes = map[string]any{}
+	}
+	if obj.References == nil {
+		obj.References = []Reference{}
+	}
+	if migrationVersion, ok := s.profile.MigrationVersions[obj.Type]; ok {
+		obj.MigrationVersion = map[string]string{obj.Type: migrationVersion}
+	}
+	obj.UpdatedAt = time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	obj.Version = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("[%d,1]", s.sequence)))
+	t.objects[key{obj.Type, obj.ID}] = obj
+
+	return obj
+}
+
+// clone returns a deep copy of the object, so objects of the caller and the server do not share attributes.
+func clone(obj Object) Object {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		panic(fmt.Sprintf("saved object %s/%s cannot be encoded: %v", obj.Type, obj.ID, err))
+	}
+	var result Object
+	if err := json.Unmarshal(data, &result); err != nil {
+		panic(fmt.Sprintf("saved object %s/%s cannot be decoded: %v", obj.Type, obj.ID, err))
+	}
+
+	return result
+}
+
+// sortedObjects returns the objects of the tenant in a stable order, the caller has to hold the lock.
+func (t *tenant) sortedObjects() []Object {
+	result := make([]Object, 0, len(t.objects))
+	for _, obj := range t.objects {
+		result = append(result, obj)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	return result
+}
+
+func (s *Server) supports(objType string) bool {
+	for _, t := range s.profile.Types {
+		if t == objType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Server) status(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"name": "fakeosd",
+		"version": map[string]any{
+			"number":       s.profile.Version,
+			"build_number": s.profile.BuildNumber,
+		},
+		"status": map[string]any{
+			"overall": map[string]any{"state": "green", "title": "Green"},
+		},
+	})
+}
+
+// errorBody is the body of failed requests and the error of single objects in bulk responses.
+type errorBody struct {
+	StatusCode int    `json:"statusCode"`
+	Error      string `json:"error"`
+	Message    string `json:"message"`
+}
+
+func newError(status int, message string) *errorBody {
+	return &errorBody{StatusCode: status, Error: http.StatusText(status), Message: message}
+}
+
+func notFound(k key) *errorBody {
+	return newError(http.StatusNotFound, fmt.Sprintf("Saved object [%s/%s] not found", k.Type, k.ID))
+}
+
+func unsupportedType(objType string) *errorBody {
+	return newError(http.StatusBadRequest, fmt.Sprintf("Unsupported saved object type: '%s': Bad Request", objType))
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, newError(status, message))
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
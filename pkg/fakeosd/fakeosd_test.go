@@ -0,0 +1,265 @@
+package fakeosd
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/advanced_settings"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+func send(t *testing.T, method, url string, body io.Reader, headers map[string]string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+
+	return res.StatusCode, string(data)
+}
+
+var xsrf = map[string]string{"osd-xsrf": "true", "Content-Type": "application/json"}
+
+func TestSavedObjectsClient(t *testing.T) {
+	s := New(Options{BasePath: "/_dashboards"})
+	defer s.Close()
+	p := saved_objects.NewSavedObjectsProvider(s.URL, http.DefaultClient, false)
+	ctx := context.Background()
+
+	dashboard := &saved_objects.SavedObjectOSD{Type: "dashboard", ID: "a", Tenant: "global", SavedObjectPostPayload: saved_objects.SavedObjectPostPayload{
+		Attributes: map[string]any{"title": "A"},
+		References: []saved_objects.Reference{{ID: "v", Name: "panel_0", Type: "visualization"}},
+	}}
+	if err := p.SaveObject(ctx, dashboard); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := p.GetObject(ctx, &saved_objects.SavedObjectOSD{Type: "dashboard", ID: "a", Tenant: "global"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Attributes != `{"title":"A"}` || len(got.References) != 1 {
+		t.Errorf("unexpected object %+v", got)
+	}
+	if _, err := p.GetObject(ctx, &saved_objects.SavedObjectOSD{Type: "dashboard", ID: "a"}); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("expected the object not to exist in other tenants but got %v", err)
+	}
+
+	found, err := p.FindObjects(ctx, saved_objects.FindOptions{Types: []string{"dashboard"}, HasReference: &saved_objects.ObjectKey{Type: "visualization", ID: "v"}, Tenant: "global"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != "a" {
+		t.Errorf("expected to find the dashboard but got %v", found)
+	}
+
+	missing, err := p.FindMissingReferences(ctx, "global", dashboard.References)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 1 {
+		t.Errorf("expected the visualization to be missing but got %v", missing)
+	}
+
+	p.BatchWrites(time.Hour, 1)
+	p.BatchReads(time.Hour, 1)
+	if err := p.SaveObject(ctx, &saved_objects.SavedObjectOSD{Type: "visualization", ID: "v", Tenant: "global"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.GetObject(ctx, &saved_objects.SavedObjectOSD{Type: "visualization", ID: "v", Tenant: "global"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.DeleteObject(ctx, dashboard); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get("global", "dashboard", "a"); ok {
+		t.Error("expected the object to be deleted")
+	}
+	if _, ok := s.Get("global", "visualization", "v"); !ok {
+		t.Error("expected the visualization to exist")
+	}
+}
+
+func TestSettingsClient(t *testing.T) {
+	s := New(Options{})
+	defer s.Close()
+	p := advanced_settings.NewProvider(s.URL, http.DefaultClient)
+	ctx := context.Background()
+
+	if err := p.SetSettings(ctx, map[string]any{"dateFormat:tz": "UTC"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.GetSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["dateFormat:tz"] != "UTC" || got["buildNum"] != float64(Profile2.BuildNumber) {
+		t.Errorf("unexpected settings %v", got)
+	}
+
+	if err := p.SetSettings(ctx, map[string]any{"dateFormat:tz": nil}); err != nil {
+		t.Fatal(err)
+	}
+	if settings := s.Settings(""); len(settings) != 0 {
+		t.Errorf("expected the setting to be reset but got %v", settings)
+	}
+}
+
+func TestXSRF(t *testing.T) {
+	s := New(Options{})
+	defer s.Close()
+
+	status, body := send(t, http.MethodPost, s.URL+"/api/saved_objects/dashboard/a", strings.NewReader(`{"attributes":{}}`), nil)
+	if status != http.StatusBadRequest || !strings.Contains(body, "osd-xsrf") {
+		t.Errorf("expected the request without osd-xsrf header to be rejected but got %d %s", status, body)
+	}
+	if status, _ := send(t, http.MethodPost, s.URL+"/api/saved_objects/dashboard/a", strings.NewReader(`{"attributes":{}}`), xsrf); status != http.StatusOK {
+		t.Errorf("expected the request to succeed but got %d", status)
+	}
+	if status, body := send(t, http.MethodPost, s.URL+"/api/saved_objects/dashboard/a", strings.NewReader(`{"attributes":{}}`), xsrf); status != http.StatusConflict {
+		t.Errorf("expected a conflict without overwrite but got %d %s", status, body)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	testCases := []struct {
+		profile    Profile
+		wantStatus int
+	}{
+		{profile: Profile1, wantStatus: http.StatusBadRequest},
+		{profile: Profile2, wantStatus: http.StatusOK},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.profile.Version, func(t *testing.T) {
+			s := New(Options{Profile: tC.profile})
+			defer s.Close()
+
+			status, body := send(t, http.MethodPost, s.URL+"/api/saved_objects/visualization-visbuilder/a", strings.NewReader(`{"attributes":{}}`), xsrf)
+			if status != tC.wantStatus {
+				t.Errorf("expected status %d but got %d %s", tC.wantStatus, status, body)
+			}
+
+			_, body = send(t, http.MethodGet, s.URL+"/api/status", nil, nil)
+			var result struct {
+				Version struct {
+					Number string `json:"number"`
+				} `json:"version"`
+			}
+			if err := json.Unmarshal([]byte(body), &result); err != nil || result.Version.Number != tC.profile.Version {
+				t.Errorf("expected version %s but got %s", tC.profile.Version, body)
+			}
+		})
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	s := New(Options{})
+	defer s.Close()
+	s.Put("", Object{Type: "index-pattern", ID: "p", Attributes: map[string]any{"title": "logs-*"}})
+	s.Put("", Object{Type: "visualization", ID: "v", Attributes: map[string]any{"title": "V"}, References: []Reference{{Name: "index", Type: "index-pattern", ID: "p"}}})
+	s.Put("", Object{Type: "dashboard", ID: "d", Attributes: map[string]any{"title": "D"}, References: []Reference{
+		{Name: "panel_0", Type: "visualization", ID: "v"},
+		{Name: "panel_1", Type: "visualization", ID: "missing"},
+	}})
+
+	status, exported := send(t, http.MethodPost, s.URL+"/api/saved_objects/_export",
+		strings.NewReader(`{"objects":[{"type":"dashboard","id":"d"}],"includeReferencesDeep":true}`), xsrf)
+	if status != http.StatusOK {
+		t.Fatalf("export failed with %d %s", status, exported)
+	}
+	lines := strings.Split(strings.TrimSpace(exported), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[3], `"exportedCount":3,"missingRefCount":1`) {
+		t.Fatalf("expected 3 objects and the details but got %s", exported)
+	}
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	file, _ := writer.CreateFormFile("file", "export.ndjson")
+	file.Write([]byte(exported))
+	writer.Close()
+	headers := map[string]string{"osd-xsrf": "true", "Content-Type": writer.FormDataContentType(), "securitytenant": "global"}
+
+	status, body := send(t, http.MethodPost, s.URL+"/api/saved_objects/_import", bytes.NewReader(form.Bytes()), headers)
+	if status != http.StatusOK || !strings.Contains(body, `"successCount":3`) {
+		t.Errorf("expected the objects to be imported into the tenant but got %d %s", status, body)
+	}
+	if _, ok := s.Get("global", "index-pattern", "p"); !ok {
+		t.Error("expected the index pattern to be imported")
+	}
+
+	status, body = send(t, http.MethodPost, s.URL+"/api/saved_objects/_import", bytes.NewReader(form.Bytes()), headers)
+	if status != http.StatusOK || !strings.Contains(body, `"success":false`) || !strings.Contains(body, `"type":"conflict"`) {
+		t.Errorf("expected conflicts without overwrite but got %d %s", status, body)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	s := New(Options{})
+	defer s.Close()
+	url := s.URL + "/api/saved_objects/_bulk_get"
+
+	s.Inject(Fault{Path: "/api/saved_objects/_bulk_get", Times: 1, Status: http.StatusTooManyRequests, RetryAfter: 1})
+	s.Inject(Fault{Method: http.MethodPost, Path: "/api/saved_objects/_bulk_get", Times: 1, Drop: true})
+	s.Inject(Fault{Path: "/api/saved_objects/_bulk_get", Times: 1, Latency: 50 * time.Millisecond})
+
+	if status, _ := send(t, http.MethodPost, url, strings.NewReader(`[]`), xsrf); status != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 but got %d", status)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(`[]`))
+	req.Header.Set("osd-xsrf", "true")
+	if res, err := http.DefaultClient.Do(req); err == nil {
+		res.Body.Close()
+		t.Error("expected the connection to be dropped")
+	}
+
+	start := time.Now()
+	if status, _ := send(t, http.MethodPost, url, strings.NewReader(`[]`), xsrf); status != http.StatusOK {
+		t.Errorf("expected the delayed request to succeed but got %d", status)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the request to be delayed but it took %v", elapsed)
+	}
+
+	if status, _ := send(t, http.MethodPost, url, strings.NewReader(`[]`), xsrf); status != http.StatusOK {
+		t.Errorf("expected the faults to be used up but got %d", status)
+	}
+	if requests := s.Requests(); len(requests) != 4 || requests[0].Path != "/api/saved_objects/_bulk_get" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
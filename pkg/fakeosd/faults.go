@@ -0,0 +1,109 @@
+package fakeosd
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fault is injected into the requests it matches, see Server.Inject.
+type Fault struct {
+	// Method and Path select the requests, empty values match all. Path is a prefix of the path relative to the
+	// base path, e.g. /api/saved_objects/_bulk_get.
+	Method string
+	Path   string
+	// Times is the number of requests the fault is injected into, all matching requests if 0
+	Times int
+
+	// Latency delays the request
+	Latency time.Duration
+	// Status fails the request with the status code, e.g. 429 or 503
+	Status int
+	// RetryAfter is sent as Retry-After header of failed requests, in seconds
+	RetryAfter int
+	// Drop closes the connection without response
+	Drop bool
+
+	injected int
+}
+
+// Inject injects the fault into the matching requests. Faults are checked in the order they were injected and
+// only the first matching one is applied.
+func (s *Server) Inject(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes all faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// fault returns the fault to inject into the request and counts it, the caller has to hold the lock.
+func (s *Server) fault(method, path string) *Fault {
+	for _, f := range s.faults {
+		if f.Times > 0 && f.injected >= f.Times {
+			continue
+		}
+		if f.Method != "" && f.Method != method {
+			continue
+		}
+		if !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+		f.injected++
+		return f
+	}
+
+	return nil
+}
+
+// apply delays the request and fails it, it returns whether the request was answered.
+func (f *Fault) apply(w http.ResponseWriter, r *http.Request) bool {
+	if f.Latency > 0 {
+		select {
+		case <-time.After(f.Latency):
+		case <-r.Context().Done():
+			return true
+		}
+	}
+
+	switch {
+	case f.Drop:
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				return true
+			}
+		}
+		panic(http.ErrAbortHandler)
+	case f.Status != 0:
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+		}
+		writeError(w, f.Status, http.StatusText(f.Status))
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,221 @@
+package fakeosd
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// exportPayload is the body of _export requests, either Type or Objects selects the objects to export.
+type exportPayload struct {
+	Type                  stringList `json:"type"`
+	Objects               []key      `json:"objects"`
+	IncludeReferencesDeep bool       `json:"includeReferencesDeep"`
+	ExcludeExportDetails  bool       `json:"excludeExportDetails"`
+}
+
+// stringList is a string or a list of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = []string{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("expected a string or a list of strings: %w", err)
+	}
+	*l = list
+
+	return nil
+}
+
+type exportDetails struct {
+	ExportedCount     int   `json:"exportedCount"`
+	MissingRefCount   int   `json:"missingRefCount"`
+	MissingReferences []key `json:"missingReferences"`
+}
+
+// exportObjects writes the selected objects as newline delimited JSON, followed by the export details. With
+// includeReferencesDeep, all objects referenced directly or indirectly are exported as well.
+func (s *Server) exportObjects(w http.ResponseWriter, r *http.Request) {
+	var body exportPayload
+	if !decode(w, r, &body) {
+		return
+	}
+	if (len(body.Type) == 0) == (len(body.Objects) == 0) {
+		writeError(w, http.StatusBadRequest, "Either `type` or `objects` are required.")
+		return
+	}
+
+	s.mu.Lock()
+	t := s.tenant(tenantOf(r))
+	var selected []Object
+	for _, objType := range body.Type {
+		if !s.supports(objType) {
+			s.mu.Unlock()
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Trying to export non-exportable type(s): %s", objType))
+			return
+		}
+	}
+	for _, obj := range t.sortedObjects() {
+		if contains(body.Type, obj.Type) {
+			selected = append(selected, obj)
+		}
+	}
+	for _, k := range body.Objects {
+		obj, ok := t.objects[k]
+		if !ok {
+			s.mu.Unlock()
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Error fetching objects to export: %s [%s] not found", k.Type, k.ID))
+			return
+		}
+		selected = append(selected, obj)
+	}
+
+	exported := map[key]bool{}
+	for _, obj := range selected {
+		exported[key{obj.Type, obj.ID}] = true
+	}
+	details := exportDetails{MissingReferences: []key{}}
+	for queue := selected; body.IncludeReferencesDeep && len(queue) > 0; {
+		obj := queue[0]
+		queue = queue[1:]
+		for _, ref := range obj.References {
+			k := key{ref.Type, ref.ID}
+			if exported[k] {
+				continue
+			}
+			exported[k] = true
+			referenced, ok := t.objects[k]
+			if !ok {
+				details.MissingReferences = append(details.MissingReferences, k)
+				continue
+			}
+			selected = append(selected, referenced)
+			queue = append(queue, referenced)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].Type != selected[j].Type {
+			return selected[i].Type < selected[j].Type
+		}
+		return selected[i].ID < selected[j].ID
+	})
+	details.ExportedCount = len(selected)
+	details.MissingRefCount = len(details.MissingReferences)
+
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	for _, obj := range selected {
+		_ = encoder.Encode(obj)
+	}
+	if !body.ExcludeExportDetails {
+		_ = encoder.Encode(details)
+	}
+	w.Header().Set("Content-Type", "application/ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="export.ndjson"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b.Bytes())
+}
+
+type importError struct {
+	ID    string         `json:"id"`
+	Type  string         `json:"type"`
+	Title string         `json:"title,omitempty"`
+	Error map[string]any `json:"error"`
+}
+
+type importSuccess struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// importObjects imports the objects of the newline delimited JSON in the form field file, as written by
+// exportObjects. Existing objects are conflicts unless overwrite is true. The export details are skipped.
+func (s *Server) importObjects(w http.ResponseWriter, r *http.Request) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("[request body.file]: %v", err))
+		return
+	}
+	defer file.Close()
+	overwrite := r.URL.Query().Get("overwrite") == "true"
+
+	var objs []Object
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var obj Object
+		if err := json.Unmarshal(line, &obj); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Unexpected token in JSON: %v", err))
+			return
+		}
+		if obj.Type == "" {
+			// the export details
+			continue
+		}
+		objs = append(objs, obj)
+	}
+	if err := scanner.Err(); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("could not read file: %v", err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tenant(tenantOf(r))
+	errs := []importError{}
+	successes := []importSuccess{}
+	for _, obj := range objs {
+		title, _ := obj.Attributes["title"].(string)
+		switch _, exists := t.objects[key{obj.Type, obj.ID}]; {
+		case !s.supports(obj.Type):
+			errs = append(errs, importError{ID: obj.ID, Type: obj.Type, Title: title, Error: map[string]any{"type": "unsupported_type"}})
+		case exists && !overwrite:
+			errs = append(errs, importError{ID: obj.ID, Type: obj.Type, Title: title, Error: map[string]any{"type": "conflict"}})
+		default:
+			s.put(t, Object{Type: obj.Type, ID: obj.ID, Attributes: obj.Attributes, References: obj.References})
+			successes = append(successes, importSuccess{ID: obj.ID, Type: obj.Type})
+		}
+	}
+
+	result := map[string]any{
+		"success":      len(errs) == 0,
+		"successCount": len(successes),
+	}
+	if len(errs) > 0 {
+		result["errors"] = errs
+	}
+	if len(successes) > 0 {
+		result["successResults"] = successes
+	}
+	writeJSON(w, http.StatusOK, result)
+}
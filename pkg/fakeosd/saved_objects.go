@@ -0,0 +1,287 @@
+package fakeosd
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// payload is the body of requests creating and updating saved objects.
+type payload struct {
+	Attributes map[string]any `json:"attributes"`
+	References []Reference    `json:"references"`
+}
+
+// bulkCreateObject is one object of a _bulk_create request.
+type bulkCreateObject struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	payload
+}
+
+// errorResult is an object of a bulk response which failed, the others are an Object.
+type errorResult struct {
+	Type  string     `json:"type"`
+	ID    string     `json:"id"`
+	Error *errorBody `json:"error"`
+}
+
+func pathKey(r *http.Request) key {
+	return key{Type: r.PathValue("type"), ID: r.PathValue("id")}
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request payload JSON format: %v", err))
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request) {
+	k := pathKey(r)
+	if !s.supports(k.Type) {
+		writeJSON(w, http.StatusBadRequest, unsupportedType(k.Type))
+		return
+	}
+
+	s.mu.Lock()
+	obj, ok := s.tenant(tenantOf(r)).objects[k]
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, notFound(k))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, obj)
+}
+
+func (s *Server) createObject(w http.ResponseWriter, r *http.Request) {
+	k := pathKey(r)
+	if !s.supports(k.Type) {
+		writeJSON(w, http.StatusBadRequest, unsupportedType(k.Type))
+		return
+	}
+	var body payload
+	if !decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tenant(tenantOf(r))
+	if _, exists := t.objects[k]; exists && r.URL.Query().Get("overwrite") != "true" {
+		writeError(w, http.StatusConflict, fmt.Sprintf("Saved object [%s/%s] conflict", k.Type, k.ID))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.put(t, Object{Type: k.Type, ID: k.ID, Attributes: body.Attributes, References: body.References}))
+}
+
+// updateObject merges the attributes into the existing object, references are replaced if given.
+func (s *Server) updateObject(w http.ResponseWriter, r *http.Request) {
+	k := pathKey(r)
+	if !s.supports(k.Type) {
+		writeJSON(w, http.StatusBadRequest, unsupportedType(k.Type))
+		return
+	}
+	var body payload
+	if !decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tenant(tenantOf(r))
+	obj, ok := t.objects[k]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, notFound(k))
+		return
+	}
+	attributes := make(map[string]any, len(obj.Attributes)+len(body.Attributes))
+	for name, value := range obj.Attributes {
+		attributes[name] = value
+	}
+	for name, value := range body.Attributes {
+		attributes[name] = value
+	}
+	obj.Attributes = attributes
+	if body.References != nil {
+		obj.References = body.References
+	}
+
+	writeJSON(w, http.StatusOK, s.put(t, obj))
+}
+
+func (s *Server) deleteObject(w http.ResponseWriter, r *http.Request) {
+	k := pathKey(r)
+	if !s.supports(k.Type) {
+		writeJSON(w, http.StatusBadRequest, unsupportedType(k.Type))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tenant(tenantOf(r))
+	if _, ok := t.objects[k]; !ok {
+		writeJSON(w, http.StatusNotFound, notFound(k))
+		return
+	}
+	delete(t.objects, k)
+
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func (s *Server) bulkGet(w http.ResponseWriter, r *http.Request) {
+	var keys []key
+	if !decode(w, r, &keys) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tenant(tenantOf(r))
+	results := make([]any, 0, len(keys))
+	for _, k := range keys {
+		obj, ok := t.objects[k]
+		switch {
+		case !s.supports(k.Type):
+			results = append(results, errorResult{k.Type, k.ID, unsupportedType(k.Type)})
+		case !ok:
+			results = append(results, errorResult{k.Type, k.ID, notFound(k)})
+		default:
+			results = append(results, obj)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"saved_objects": results})
+}
+
+func (s *Server) bulkCreate(w http.ResponseWriter, r *http.Request) {
+	var objs []bulkCreateObject
+	if !decode(w, r, &objs) {
+		return
+	}
+	overwrite := r.URL.Query().Get("overwrite") == "true"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tenant(tenantOf(r))
+	results := make([]any, 0, len(objs))
+	for _, o := range objs {
+		k := key{Type: o.Type, ID: o.ID}
+		if !s.supports(k.Type) {
+			results = append(results, errorResult{k.Type, k.ID, unsupportedType(k.Type)})
+			continue
+		}
+		if _, exists := t.objects[k]; exists && !overwrite {
+			results = append(results, errorResult{k.Type, k.ID, newError(http.StatusConflict, fmt.Sprintf("Saved object [%s/%s] conflict", k.Type, k.ID))})
+			continue
+		}
+		results = append(results, s.put(t, Object{Type: k.Type, ID: k.ID, Attributes: o.Attributes, References: o.References}))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"saved_objects": results})
+}
+
+// hasReference is the has_reference parameter of _find.
+type hasReference struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// find supports the parameters type, has_reference, search on the title, page and per_page.
+func (s *Server) find(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	types := query["type"]
+	if len(types) == 0 {
+		writeError(w, http.StatusBadRequest, "[request query.type]: expected at least one defined value but got [undefined]")
+		return
+	}
+	var reference *hasReference
+	if v := query.Get("has_reference"); v != "" {
+		reference = &hasReference{}
+		if err := json.Unmarshal([]byte(v), reference); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("[request query.has_reference]: %v", err))
+			return
+		}
+	}
+	page, perPage := 1, 20
+	for name, target := range map[string]*int{"page": &page, "per_page": &perPage} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("[request query.%s]: expected value of type [number] but got [%s]", name, v))
+				return
+			}
+			*target = n
+		}
+	}
+	search := strings.ToLower(strings.Trim(query.Get("search"), "*"))
+
+	s.mu.Lock()
+	var matched []Object
+	for _, obj := range s.tenant(tenantOf(r)).sortedObjects() {
+		if !contains(types, obj.Type) {
+			continue
+		}
+		if reference != nil && !references(obj, *reference) {
+			continue
+		}
+		if title, _ := obj.Attributes["title"].(string); search != "" && !strings.Contains(strings.ToLower(title), search) {
+			continue
+		}
+		matched = append(matched, obj)
+	}
+	s.mu.Unlock()
+
+	result := []Object{}
+	if start := (page - 1) * perPage; start < len(matched) {
+		result = matched[start:min(start+perPage, len(matched))]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"page":          page,
+		"per_page":      perPage,
+		"total":         len(matched),
+		"saved_objects": result,
+	})
+}
+
+func references(obj Object, reference hasReference) bool {
+	for _, ref := range obj.References {
+		if ref.Type == reference.Type && ref.ID == reference.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(list []string, v string) bool {
+	for _, element := range list {
+		if element == v {
+			return true
+		}
+	}
+
+	return false
+}
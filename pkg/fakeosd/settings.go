@@ -0,0 +1,78 @@
+package fakeosd
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"net/http"
+)
+
+// settingsPayload is the body of requests changing settings, a null value resets the setting to its default.
+type settingsPayload struct {
+	Changes map[string]any `json:"changes"`
+}
+
+type userValue struct {
+	UserValue any `json:"userValue"`
+}
+
+func (s *Server) getSettings(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, s.settingsResponse(s.tenant(tenantOf(r))))
+}
+
+func (s *Server) setSettings(w http.ResponseWriter, r *http.Request) {
+	var body settingsPayload
+	if !decode(w, r, &body) {
+		return
+	}
+	if body.Changes == nil {
+		writeError(w, http.StatusBadRequest, "[request body.changes]: expected value of type [object] but got [undefined]")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tenant(tenantOf(r))
+	for name := range body.Changes {
+		if name == "buildNum" {
+			writeError(w, http.StatusBadRequest, "Unable to update \"buildNum\" because it is read-only")
+			return
+		}
+	}
+	for name, value := range body.Changes {
+		if value == nil {
+			delete(t.settings, name)
+		} else {
+			t.settings[name] = value
+		}
+	}
+
+	writeJSON(w, http.StatusOK, s.settingsResponse(t))
+}
+
+// settingsResponse returns the settings changed by a user and the build number, which every config object has.
+// The caller has to hold the lock.
+func (s *Server) settingsResponse(t *tenant) map[string]any {
+	settings := map[string]userValue{"buildNum": {UserValue: s.profile.BuildNumber}}
+	for name, value := range t.settings {
+		settings[name] = userValue{UserValue: value}
+	}
+
+	return map[string]any{"settings": settings}
+}
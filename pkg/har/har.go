@@ -0,0 +1,398 @@
+// Package har records the HTTP traffic of the provider in HTTP Archive 1.2 files, which can be opened in the
+// developer tools of browsers.
+package har
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/httplog"
+)
+
+const (
+	version = "1.2"
+	// suffix closes the entries, the log and the document. Entries are appended in front of it.
+	suffix = "]}}"
+)
+
+type document struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string          `json:"version"`
+	Creator creator         `json:"creator"`
+	Entries json.RawMessage `json:"entries"`
+}
+
+type creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type Entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	// Time is the total time of the request in milliseconds
+	Time     float64  `json:"time"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+	Cache    struct{} `json:"cache"`
+	Timings  Timings  `json:"timings"`
+}
+
+type Request struct {
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	HTTPVersion string    `json:"httpVersion"`
+	Cookies     []NVP     `json:"cookies"`
+	Headers     []NVP     `json:"headers"`
+	QueryString []NVP     `json:"queryString"`
+	PostData    *PostData `json:"postData,omitempty"`
+	HeadersSize int       `json:"headersSize"`
+	BodySize    int       `json:"bodySize"`
+}
+
+type Response struct {
+	Status      int     `json:"status"`
+	StatusText  string  `json:"statusText"`
+	HTTPVersion string  `json:"httpVersion"`
+	Cookies     []NVP   `json:"cookies"`
+	Headers     []NVP   `json:"headers"`
+	Content     Content `json:"content"`
+	RedirectURL string  `json:"redirectURL"`
+	HeadersSize int     `json:"headersSize"`
+	BodySize    int     `json:"bodySize"`
+}
+
+// NVP is a name value pair of headers, cookies and query strings.
+type NVP struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type Content struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+type Timings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// Recorder appends entries to a HAR file. The file is a complete HAR document after every entry, so it can be
+// opened while Terraform is still running. Entries are appended to an existing HAR file, e.g. from the plan.
+// Every entry is written under an exclusive lock of the file, so several provider processes can share it.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	// header is written to empty files
+	header []byte
+	// end is the offset of the suffix, empty is set while there are no entries
+	end   int64
+	empty bool
+	// size is the size of the file after the last write of this Recorder, -1 before the first one
+	size int64
+}
+
+var (
+	recordersMu sync.Mutex
+	recorders   = map[string]*Recorder{}
+)
+
+// Open returns the Recorder for the file. All providers of a process share one Recorder per file. Existing files
+// which are not HAR files written by a Recorder are not touched and an error is returned.
+func Open(path, creatorVersion string) (*Recorder, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HAR file path %s: %w", path, err)
+	}
+
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+	if r, ok := recorders[abs]; ok {
+		return r, nil
+	}
+
+	r, err := openRecorder(abs, creatorVersion)
+	if err != nil {
+		return nil, err
+	}
+	recorders[abs] = r
+
+	return r, nil
+}
+
+func openRecorder(path, creatorVersion string) (*Recorder, error) {
+	header, err := json.Marshal(document{Log: harLog{
+		Version: version,
+		Creator: creator{Name: "terraform-provider-opensearch-dashboards", Version: creatorVersion},
+		Entries: json.RawMessage("[]"),
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode HAR file: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open HAR file: %w", err)
+	}
+
+	r := &Recorder{file: f, header: header, size: -1}
+	if err := r.locked(r.sync); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// locked calls fn while holding the lock of the file.
+func (r *Recorder) locked(fn func() error) error {
+	if err := lockFile(r.file); err != nil {
+		return fmt.Errorf("could not lock HAR file %s: %w", r.file.Name(), err)
+	}
+	defer unlockFile(r.file)
+
+	return fn()
+}
+
+// sync finds the suffix again if the file was changed by another process since the last write of this Recorder.
+// Empty files are started with the header. The caller has to hold the lock of the file.
+func (r *Recorder) sync() error {
+	info, err := r.file.Stat()
+	if err != nil {
+		return fmt.Errorf("could not read HAR file %s: %w", r.file.Name(), err)
+	}
+	if info.Size() == r.size {
+		return nil
+	}
+
+	if info.Size() == 0 {
+		if _, err := r.file.WriteAt(r.header, 0); err != nil {
+			return fmt.Errorf("could not write HAR file %s: %w", r.file.Name(), err)
+		}
+		r.end, r.empty, r.size = int64(len(r.header)-len(suffix)), true, int64(len(r.header))
+		return nil
+	}
+
+	end, empty, ok := findSuffix(io.NewSectionReader(r.file, 0, info.Size()))
+	if !ok {
+		return fmt.Errorf("%s is not a HAR file written by the provider, it is not overwritten", r.file.Name())
+	}
+	r.end, r.empty, r.size = end, empty, info.Size()
+
+	return nil
+}
+
+// findSuffix returns the offset of the suffix of a HAR file and whether it has no entries yet.
+func findSuffix(f io.Reader) (int64, bool, bool) {
+	data, err := io.ReadAll(f)
+	if err != nil || !json.Valid(data) {
+		return 0, false, false
+	}
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil || doc.Log.Version != version {
+		return 0, false, false
+	}
+	trimmed := bytes.TrimRight(data, " \n\r\t")
+	if !bytes.HasSuffix(trimmed, []byte(suffix)) {
+		return 0, false, false
+	}
+	end := int64(len(trimmed) - len(suffix))
+	empty := bytes.HasSuffix(bytes.TrimRight(trimmed[:end], " \n\r\t"), []byte("["))
+
+	return end, empty, true
+}
+
+// Add appends the entry to the file.
+func (r *Recorder) Add(entry Entry) error {
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not encode HAR entry: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.locked(func() error {
+		if err := r.sync(); err != nil {
+			return err
+		}
+
+		var b bytes.Buffer
+		if !r.empty {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+		b.Write(encoded)
+		entryEnd := r.end + int64(b.Len())
+		b.WriteString(suffix)
+		if _, err := r.file.WriteAt(b.Bytes(), r.end); err != nil {
+			return fmt.Errorf("could not write HAR entry: %w", err)
+		}
+		r.end = entryEnd
+		r.empty = false
+		r.size = entryEnd + int64(len(suffix))
+
+		return nil
+	})
+}
+
+// Transport records every request and its response with a Recorder. Sensitive headers and attributes are
+// redacted. It is meant to be the outermost round-tripper, so requests are recorded before they are signed.
+type Transport struct {
+	next     http.RoundTripper
+	recorder *Recorder
+	redactor *httplog.Redactor
+}
+
+// NewTransport instantiates a new recording middleware with an optional succeeding middleware. The
+// http.DefaultTransport will be used if nil.
+func NewTransport(next http.RoundTripper, recorder *Recorder, redactor *httplog.Redactor) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Transport{next: next, recorder: recorder, redactor: redactor}
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := Entry{
+		StartedDateTime: time.Now(),
+		Request: Request{
+			Method:      req.Method,
+			URL:         req.URL.Redacted(),
+			HTTPVersion: req.Proto,
+			Cookies:     []NVP{},
+			Headers:     t.headers(req.Header),
+			QueryString: t.query(req),
+			HeadersSize: -1,
+			BodySize:    0,
+		},
+	}
+	if entry.Request.HTTPVersion == "" {
+		entry.Request.HTTPVersion = "HTTP/1.1"
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, readErr := io.ReadAll(body)
+			body.Close()
+			if readErr == nil {
+				entry.Request.BodySize = len(data)
+				entry.Request.PostData = &PostData{MimeType: req.Header.Get("Content-Type"), Text: t.redactor.Body(data)}
+			}
+		}
+	}
+
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+	elapsed := float64(time.Since(start).Microseconds()) / 1000
+	entry.Time = elapsed
+	entry.Timings = Timings{Send: 0, Wait: elapsed, Receive: 0}
+	if err != nil {
+		// failed requests are recorded without response, like browsers do
+		entry.Response = Response{StatusText: err.Error(), HTTPVersion: entry.Request.HTTPVersion, Cookies: []NVP{}, Headers: []NVP{}, HeadersSize: -1, BodySize: -1}
+		t.add(req.Context(), entry)
+
+		//nolint: wrapcheck
+		return nil, err
+	}
+
+	body, readErr := io.ReadAll(res.Body)
+	res.Body.Close()
+	var replay io.Reader = bytes.NewReader(body)
+	if readErr != nil {
+		replay = io.MultiReader(replay, errReader{readErr})
+	}
+	res.Body = io.NopCloser(replay)
+
+	entry.Response = Response{
+		Status:      res.StatusCode,
+		StatusText:  http.StatusText(res.StatusCode),
+		HTTPVersion: res.Proto,
+		Cookies:     []NVP{},
+		Headers:     t.headers(res.Header),
+		Content: Content{
+			Size:     len(body),
+			MimeType: res.Header.Get("Content-Type"),
+			Text:     t.redactor.Body(body),
+		},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	t.add(req.Context(), entry)
+
+	return res, nil
+}
+
+// add records the entry, a failure is logged but does not affect the request.
+func (t *Transport) add(ctx context.Context, entry Entry) {
+	if err := t.recorder.Add(entry); err != nil {
+		tflog.Warn(ctx, "could not record HTTP request in HAR file", map[string]any{"error": err.Error()})
+	}
+}
+
+func (t *Transport) headers(header http.Header) []NVP {
+	result := []NVP{}
+	for name, values := range header {
+		for _, value := range values {
+			result = append(result, NVP{Name: name, Value: t.redactor.Header(name, value)})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result
+}
+
+func (t *Transport) query(req *http.Request) []NVP {
+	result := []NVP{}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			result = append(result, NVP{Name: name, Value: value})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
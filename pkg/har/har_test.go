@@ -0,0 +1,213 @@
+package har
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/httplog"
+)
+
+type harFile struct {
+	Log struct {
+		Version string  `json:"version"`
+		Entries []Entry `json:"entries"`
+	} `json:"log"`
+}
+
+func readHAR(t *testing.T, path string) harFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result harFile
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("expected valid HAR file but got %v: %s", err, data)
+	}
+	if result.Log.Version != "1.2" {
+		t.Errorf("expected HAR version 1.2 but got %s", result.Log.Version)
+	}
+
+	return result
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusConflict)
+		}
+		w.Write([]byte(`{"attributes":{"title":"a","description":"secret-description"}}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "provider.har")
+	recorder, err := openRecorder(path, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: NewTransport(nil, recorder, httplog.NewRedactor([]string{"attributes.description"}))}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/saved_objects/dashboard/a?fields=title", nil)
+	req.Header.Set("Authorization", "Basic secret-password")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.Contains(string(body), "secret-description") {
+		t.Errorf("expected the response body to be passed on unchanged but got %s", body)
+	}
+
+	if got := readHAR(t, path); len(got.Log.Entries) != 1 {
+		t.Fatalf("expected the file to be valid after the first entry but got %d entries", len(got.Log.Entries))
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, srv.URL+"/api/saved_objects/dashboard/a", strings.NewReader(`{"attributes":{"description":"secret-request"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	res, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("expected credentials and sensitive attributes to be redacted but got %s", data)
+	}
+
+	got := readHAR(t, path)
+	if len(got.Log.Entries) != 2 {
+		t.Fatalf("expected 2 entries but got %d", len(got.Log.Entries))
+	}
+	get, post := got.Log.Entries[0], got.Log.Entries[1]
+	if get.Request.Method != http.MethodGet || get.Response.Status != http.StatusOK || get.Request.QueryString[0] != (NVP{Name: "fields", Value: "title"}) {
+		t.Errorf("unexpected GET entry %+v", get)
+	}
+	if get.Request.Headers[0] != (NVP{Name: "Authorization", Value: "***"}) {
+		t.Errorf("expected the Authorization header to be redacted but got %v", get.Request.Headers)
+	}
+	if post.Response.Status != http.StatusConflict || post.Request.PostData == nil || post.Request.PostData.Text != `{"attributes":{"description":"***"}}` {
+		t.Errorf("unexpected POST entry %+v", post)
+	}
+	if post.Response.Content.Text != `{"attributes":{"description":"***","title":"a"}}` || post.Response.Content.MimeType != "application/json" {
+		t.Errorf("unexpected response content %+v", post.Response.Content)
+	}
+}
+
+func TestRecorderAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider.har")
+
+	for i := 1; i <= 2; i++ {
+		recorder, err := openRecorder(path, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := recorder.Add(Entry{Request: Request{Method: http.MethodGet}}); err != nil {
+			t.Fatal(err)
+		}
+		recorder.file.Close()
+
+		if got := readHAR(t, path); len(got.Log.Entries) != i {
+			t.Errorf("expected %d entries but got %d", i, len(got.Log.Entries))
+		}
+	}
+}
+
+func TestRecorderRejectsOtherFiles(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		content string
+	}{
+		{
+			desc:    "must not overwrite truncated HAR files",
+			content: `{"log":{"version":"1.2","entries":[{"truncated`,
+		},
+		{
+			desc:    "must not overwrite other files",
+			content: `{"version":4,"terraform_version":"1.5.7"}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "provider.har")
+			if err := os.WriteFile(path, []byte(tC.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := openRecorder(path, "test"); err == nil {
+				t.Error("expected an error but got none")
+			}
+			if got, _ := os.ReadFile(path); string(got) != tC.content {
+				t.Errorf("expected the file to be unchanged but got %s", got)
+			}
+		})
+	}
+}
+
+func TestRecorderStartsEmptyFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider.har")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder, err := openRecorder(path, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.file.Close()
+	if err := recorder.Add(Entry{Request: Request{Method: http.MethodGet}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readHAR(t, path); len(got.Log.Entries) != 1 {
+		t.Errorf("expected 1 entry but got %d", len(got.Log.Entries))
+	}
+}
+
+// TestRecorderShared simulates two provider processes, which write to the same file with their own Recorder.
+func TestRecorderShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider.har")
+	var recorders []*Recorder
+	for range 2 {
+		recorder, err := openRecorder(path, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer recorder.file.Close()
+		recorders = append(recorders, recorder)
+	}
+
+	for i := range 6 {
+		if err := recorders[i%2].Add(Entry{Request: Request{Method: http.MethodGet}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := readHAR(t, path); len(got.Log.Entries) != 6 {
+		t.Errorf("expected 6 entries but got %d", len(got.Log.Entries))
+	}
+}
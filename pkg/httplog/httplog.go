@@ -0,0 +1,294 @@
+// Package httplog logs the HTTP traffic of the provider with tflog, in the subsystem http.
+package httplog
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// Subsystem is the tflog subsystem of the HTTP logs. Its level can be set with TF_LOG_PROVIDER_OPENSEARCH_HTTP.
+	Subsystem = "http"
+	levelEnv  = "TF_LOG_PROVIDER_OPENSEARCH"
+	redacted  = "***"
+	// maxBodySize is the number of bytes of a body which are logged
+	maxBodySize = 64 << 10
+)
+
+// sensitiveHeaders are always redacted.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":        true,
+	"Proxy-Authorization":  true,
+	"X-Amz-Security-Token": true,
+	"Cookie":               true,
+	"Set-Cookie":           true,
+}
+
+type Options struct {
+	// SensitivePaths are dotted paths of JSON attributes in request and response bodies which are redacted,
+	// e.g. attributes.description. They match at any depth and arrays are traversed, so the example matches
+	// single saved objects as well as the objects of bulk requests and responses.
+	SensitivePaths []string
+	// LogBodies enables logging headers and bodies at TRACE level. Bodies are read into memory for that.
+	LogBodies bool
+}
+
+// Transport logs method, path, status, latency, attempt and tenant of every request at DEBUG level.
+type Transport struct {
+	next      http.RoundTripper
+	redactor  *Redactor
+	logBodies bool
+}
+
+// NewTransport instantiates a new logging middleware with an optional succeeding middleware. The
+// http.DefaultTransport will be used if nil.
+func NewTransport(next http.RoundTripper, opts Options) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Transport{next: next, redactor: NewRedactor(opts.SensitivePaths), logBodies: opts.LogBodies}
+}
+
+// TraceFromEnv reports whether the http subsystem logs at TRACE level according to the environment, so
+// bodies are only read when they are logged.
+func TraceFromEnv() bool {
+	for _, env := range []string{levelEnv + "_HTTP", "TF_LOG_PROVIDER", "TF_LOG"} {
+		if level := os.Getenv(env); level != "" {
+			return strings.EqualFold(level, "TRACE")
+		}
+	}
+
+	return false
+}
+
+type attemptsKey struct{}
+
+// WithAttempts returns a context which counts the requests sent with it. Requests which are sent again with the
+// same context, e.g. after a redirect or by a retry, are logged with their attempt, the first one is 1.
+func WithAttempts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, attemptsKey{}, new(atomic.Int32))
+}
+
+func nextAttempt(ctx context.Context) int {
+	if attempts, ok := ctx.Value(attemptsKey{}).(*atomic.Int32); ok {
+		return int(attempts.Add(1))
+	}
+
+	return 1
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := tflog.NewSubsystem(req.Context(), Subsystem, tflog.WithLevelFromEnv(levelEnv, strings.ToUpper(Subsystem)))
+
+	fields := map[string]any{
+		"method":  req.Method,
+		"path":    req.URL.Path,
+		"attempt": nextAttempt(ctx),
+	}
+	if tenant := req.Header.Get("securitytenant"); tenant != "" {
+		fields["tenant"] = tenant
+	}
+
+	if t.logBodies {
+		requestFields := map[string]any{"headers": t.headers(req.Header)}
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				requestFields["body"] = t.body(body)
+			}
+		}
+		tflog.SubsystemTrace(ctx, Subsystem, "sending HTTP request", fields, requestFields)
+	}
+
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+	fields["latency_ms"] = time.Since(start).Milliseconds()
+	if err != nil {
+		fields["error"] = err.Error()
+		tflog.SubsystemDebug(ctx, Subsystem, "HTTP request failed", fields)
+
+		//nolint: wrapcheck
+		return nil, err
+	}
+
+	fields["status"] = res.StatusCode
+	tflog.SubsystemDebug(ctx, Subsystem, "HTTP request", fields)
+
+	if t.logBodies {
+		responseFields := map[string]any{"headers": t.headers(res.Header)}
+		if res.Body != nil && res.Body != http.NoBody {
+			body, readErr := io.ReadAll(res.Body)
+			res.Body.Close()
+			// the caller reads the same body and gets the same error, if any
+			var replay io.Reader = bytes.NewReader(body)
+			if readErr != nil {
+				replay = io.MultiReader(replay, errReader{readErr})
+			}
+			res.Body = io.NopCloser(replay)
+			responseFields["body"] = t.body(io.NopCloser(bytes.NewReader(body)))
+		}
+		tflog.SubsystemTrace(ctx, Subsystem, "received HTTP response", fields, responseFields)
+	}
+
+	return res, nil
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// headers returns the headers as sorted key value pairs with the sensitive headers redacted.
+func (t *Transport) headers(header http.Header) []string {
+	result := make([]string, 0, len(header))
+	for key, values := range header {
+		result = append(result, key+": "+t.redactor.Header(key, strings.Join(values, ",")))
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// body returns the body with the sensitive paths redacted.
+func (t *Transport) body(body io.ReadCloser) string {
+	defer body.Close()
+	raw, err := io.ReadAll(io.LimitReader(body, maxBodySize+1))
+	if err != nil {
+		return ""
+	}
+	if len(raw) > maxBodySize {
+		// a truncated body cannot be parsed, so no attribute can be redacted
+		if t.redactor.hasPaths() {
+			return redacted
+		}
+		return string(raw[:maxBodySize]) + "..."
+	}
+
+	return t.redactor.Body(raw)
+}
+
+// Redactor removes credentials and sensitive attributes from requests and responses before they are recorded.
+type Redactor struct {
+	paths [][]string
+}
+
+// NewRedactor returns a Redactor for the dotted paths of sensitive JSON attributes, see Options.SensitivePaths.
+func NewRedactor(sensitivePaths []string) *Redactor {
+	paths := make([][]string, 0, len(sensitivePaths))
+	for _, path := range sensitivePaths {
+		paths = append(paths, strings.Split(path, "."))
+	}
+
+	return &Redactor{paths: paths}
+}
+
+func (r *Redactor) hasPaths() bool {
+	return len(r.paths) > 0
+}
+
+// Header returns the value of the header, or *** if the header holds credentials.
+func (r *Redactor) Header(name, value string) string {
+	if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+		return redacted
+	}
+
+	return value
+}
+
+// Body returns the body with the sensitive attributes redacted.
+func (r *Redactor) Body(data []byte) string {
+	return redactJSON(data, r.paths)
+}
+
+// redactJSON replaces the values at the paths in JSON or newline delimited JSON data with ***. Without paths
+// the data is returned as it is, otherwise data which is not JSON is replaced completely.
+func redactJSON(data []byte, paths [][]string) string {
+	if len(paths) == 0 || len(bytes.TrimSpace(data)) == 0 {
+		return string(data)
+	}
+
+	documents := [][]byte{data}
+	if !json.Valid(data) {
+		documents = bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	}
+	result := make([]string, 0, len(documents))
+	for _, document := range documents {
+		var v any
+		if err := json.Unmarshal(document, &v); err != nil {
+			// the attributes cannot be found, so nothing is logged which could contain them
+			return redacted
+		}
+		for _, path := range paths {
+			redactPath(v, path)
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return redacted
+		}
+		result = append(result, string(encoded))
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// redactPath redacts the path starting at v and at every object nested in v.
+func redactPath(v any, path []string) {
+	switch value := v.(type) {
+	case map[string]any:
+		redactPathAt(value, path)
+		for _, child := range value {
+			redactPath(child, path)
+		}
+	case []any:
+		for _, element := range value {
+			redactPath(element, path)
+		}
+	}
+}
+
+func redactPathAt(v any, path []string) {
+	switch value := v.(type) {
+	case map[string]any:
+		child, ok := value[path[0]]
+		if !ok {
+			return
+		}
+		if len(path) == 1 {
+			value[path[0]] = redacted
+			return
+		}
+		redactPathAt(child, path[1:])
+	case []any:
+		for _, element := range value {
+			redactPathAt(element, path)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package httplog
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Set-Cookie", "security_authentication=secret-cookie")
+		w.Write([]byte(`{"saved_objects":[{"id":"a","attributes":{"title":"a","description":"secret-description"}}]}`))
+	}))
+	defer srv.Close()
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	ctx = WithAttempts(ctx)
+
+	client := &http.Client{Transport: NewTransport(nil, Options{
+		SensitivePaths: []string{"attributes.description"},
+		LogBodies:      true,
+	})}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/_dashboards/api/saved_objects/_bulk_get?x=1",
+		strings.NewReader(`[{"type":"dashboard","id":"a","attributes":{"description":"secret-request"}}]`))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=secret-credential")
+	req.Header.Set("X-Amz-Security-Token", "secret-token")
+	req.Header.Set("securitytenant", "global")
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.Contains(string(body), "secret-description") {
+		t.Errorf("expected the response body to be passed on unchanged but got %s", body)
+	}
+
+	if strings.Contains(output.String(), "secret") {
+		t.Errorf("expected sensitive values to be redacted but got %s", output.String())
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected request, summary and response to be logged but got %v", entries)
+	}
+	summary := entries[1]
+	want := map[string]any{
+		"@level":   "debug",
+		"@module":  "provider.http",
+		"@message": "HTTP request",
+		"method":   "POST",
+		"path":     "/_dashboards/api/saved_objects/_bulk_get",
+		"status":   float64(200),
+		"attempt":  float64(1),
+		"tenant":   "global",
+	}
+	for key, value := range want {
+		if summary[key] != value {
+			t.Errorf("expected %s to be %v but got %v", key, value, summary[key])
+		}
+	}
+	if _, ok := summary["latency_ms"]; !ok {
+		t.Error("expected latency to be logged")
+	}
+	if _, ok := summary["body"]; ok {
+		t.Error("expected no body at debug level")
+	}
+	if entries[2]["@level"] != "trace" || !strings.Contains(entries[2]["body"].(string), `"description":"***"`) {
+		t.Errorf("expected the redacted response body at trace level but got %v", entries[2])
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	client := &http.Client{Transport: NewTransport(nil, Options{})}
+	for _, ctx := range []context.Context{WithAttempts(ctx), ctx} {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/old", nil)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attempts []any
+	for _, entry := range entries {
+		attempts = append(attempts, entry["attempt"])
+	}
+	// without counter in the context every request is the first attempt
+	want := []any{float64(1), float64(2), float64(1), float64(1)}
+	if !reflect.DeepEqual(attempts, want) {
+		t.Errorf("expected attempts %v but got %v", want, attempts)
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	paths := [][]string{{"attributes", "description"}, {"token"}}
+	testCases := []struct {
+		desc  string
+		data  string
+		paths [][]string
+		want  string
+	}{
+		{
+			desc: "must redact paths at any depth",
+			data: `{"saved_objects":[{"attributes":{"description":"x","title":"t"}}],"token":"x"}`,
+			want: `{"saved_objects":[{"attributes":{"description":"***","title":"t"}}],"token":"***"}`,
+		},
+		{
+			desc: "must redact paths in arrays of objects",
+			data: `[{"attributes":{"description":"x"}},{"attributes":{}}]`,
+			want: `[{"attributes":{"description":"***"}},{"attributes":{}}]`,
+		},
+		{
+			desc: "must redact newline delimited JSON",
+			data: "{\"attributes\":{\"description\":\"x\"}}\n{\"token\":\"x\"}\n",
+			want: "{\"attributes\":{\"description\":\"***\"}}\n{\"token\":\"***\"}",
+		},
+		{
+			desc: "must redact data which is not JSON completely",
+			data: `description=x`,
+			want: `***`,
+		},
+		{
+			desc:  "must not touch data without paths",
+			data:  `description=x`,
+			paths: [][]string{},
+			want:  `description=x`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := paths
+			if tC.paths != nil {
+				p = tC.paths
+			}
+			if got := redactJSON([]byte(tC.data), p); got != tC.want {
+				t.Errorf("expected %s but got %s", tC.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+const (
+	searchSourceIndexRefName = "kibanaSavedObjectMeta.searchSourceJSON.index"
+	searchSourceFilterRefFmt = "kibanaSavedObjectMeta.searchSourceJSON.filter[%d].meta.index"
+	savedSearchRefName       = "search_0"
+	panelRefNameFmt          = "panel_%d"
+	controlRefNameFmt        = "control_%d_index_pattern"
+
+	searchType = "search"
+)
+
+// ExtractReferences moves object IDs written inline into the attributes to references, the same way the
+// saved objects client of OpenSearch Dashboards does before an object is saved. Supported are the index
+// and the filters of searchSourceJSON, the panels of panelsJSON, savedSearchId and the index patterns of
+// input controls in visState. The attributes are not modified, a copy is returned.
+func ExtractReferences(attributes map[string]any) (map[string]any, []Reference, error) {
+	result := copyMap(attributes)
+	var refs []Reference
+
+	err := updateNestedJSON(result, func(source map[string]any) error {
+		if index, ok := source["index"].(string); ok && index != "" {
+			delete(source, "index")
+			source["indexRefName"] = searchSourceIndexRefName
+			refs = append(refs, Reference{ID: index, Name: searchSourceIndexRefName, Type: indexPatternType})
+		}
+
+		filters, _ := source["filter"].([]any)
+		for i, f := range filters {
+			meta := nestedMap(f, "meta")
+			if index, ok := meta["index"].(string); ok && index != "" {
+				name := fmt.Sprintf(searchSourceFilterRefFmt, i)
+				delete(meta, "index")
+				meta["indexRefName"] = name
+				refs = append(refs, Reference{ID: index, Name: name, Type: indexPatternType})
+			}
+		}
+		return nil
+	}, "kibanaSavedObjectMeta", "searchSourceJSON")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if panelsJSON, ok := result["panelsJSON"].(string); ok {
+		var panels []any
+		if err := json.Unmarshal([]byte(panelsJSON), &panels); err != nil {
+			return nil, nil, fmt.Errorf("panelsJSON is not a valid JSON array: %w", err)
+		}
+		for i, p := range panels {
+			panel, _ := p.(map[string]any)
+			id, hasID := panel["id"].(string)
+			panelType, hasType := panel["type"].(string)
+			if !hasID || !hasType {
+				continue
+			}
+			name := fmt.Sprintf(panelRefNameFmt, i)
+			delete(panel, "id")
+			delete(panel, "type")
+			panel["panelRefName"] = name
+			refs = append(refs, Reference{ID: id, Name: name, Type: panelType})
+		}
+		encoded, err := json.Marshal(panels)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not encode panelsJSON: %w", err)
+		}
+		result["panelsJSON"] = string(encoded)
+	}
+
+	if id, ok := result["savedSearchId"].(string); ok && id != "" {
+		delete(result, "savedSearchId")
+		result["savedSearchRefName"] = savedSearchRefName
+		refs = append(refs, Reference{ID: id, Name: savedSearchRefName, Type: searchType})
+	}
+
+	err = updateNestedJSON(result, func(visState map[string]any) error {
+		controls, _ := nestedMap(visState, "params")["controls"].([]any)
+		for i, c := range controls {
+			control, _ := c.(map[string]any)
+			if id, ok := control["indexPattern"].(string); ok && id != "" {
+				name := fmt.Sprintf(controlRefNameFmt, i)
+				delete(control, "indexPattern")
+				control["indexPatternRefName"] = name
+				refs = append(refs, Reference{ID: id, Name: name, Type: indexPatternType})
+			}
+		}
+		return nil
+	}, "visState")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, refs, nil
+}
+
+// InjectReferences is the inverse of ExtractReferences. It writes the IDs of the references back into the
+// attributes and returns the references which could not be injected.
+func InjectReferences(attributes map[string]any, refs []Reference) (map[string]any, []Reference, error) {
+	result := copyMap(attributes)
+	byName := make(map[string]Reference, len(refs))
+	for _, ref := range refs {
+		byName[ref.Name] = ref
+	}
+	consumed := map[string]bool{}
+	lookup := func(name any) (Reference, bool) {
+		n, ok := name.(string)
+		if !ok {
+			return Reference{}, false
+		}
+		ref, ok := byName[n]
+		if ok {
+			consumed[n] = true
+		}
+		return ref, ok
+	}
+
+	err := updateNestedJSON(result, func(source map[string]any) error {
+		if ref, ok := lookup(source["indexRefName"]); ok {
+			delete(source, "indexRefName")
+			source["index"] = ref.ID
+		}
+
+		filters, _ := source["filter"].([]any)
+		for _, f := range filters {
+			meta := nestedMap(f, "meta")
+			if ref, ok := lookup(meta["indexRefName"]); ok {
+				delete(meta, "indexRefName")
+				meta["index"] = ref.ID
+			}
+		}
+		return nil
+	}, "kibanaSavedObjectMeta", "searchSourceJSON")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if panelsJSON, ok := result["panelsJSON"].(string); ok {
+		var panels []any
+		if err := json.Unmarshal([]byte(panelsJSON), &panels); err != nil {
+			return nil, nil, fmt.Errorf("panelsJSON is not a valid JSON array: %w", err)
+		}
+		for _, p := range panels {
+			panel, _ := p.(map[string]any)
+			if ref, ok := lookup(panel["panelRefName"]); ok {
+				delete(panel, "panelRefName")
+				panel["id"] = ref.ID
+				panel["type"] = ref.Type
+			}
+		}
+		encoded, err := json.Marshal(panels)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not encode panelsJSON: %w", err)
+		}
+		result["panelsJSON"] = string(encoded)
+	}
+
+	if ref, ok := lookup(result["savedSearchRefName"]); ok {
+		delete(result, "savedSearchRefName")
+		result["savedSearchId"] = ref.ID
+	}
+
+	err = updateNestedJSON(result, func(visState map[string]any) error {
+		controls, _ := nestedMap(visState, "params")["controls"].([]any)
+		for _, c := range controls {
+			control, _ := c.(map[string]any)
+			if ref, ok := lookup(control["indexPatternRefName"]); ok {
+				delete(control, "indexPatternRefName")
+				control["indexPattern"] = ref.ID
+			}
+		}
+		return nil
+	}, "visState")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var remaining []Reference
+	for _, ref := range refs {
+		if !consumed[ref.Name] {
+			remaining = append(remaining, ref)
+		}
+	}
+
+	return result, remaining, nil
+}
+
+// EquivalentAttributes reports whether two stringified attributes are semantically equal. Stringified JSON
+// nested in the attributes is compared by value as well, so formatting and key order do not matter.
+func EquivalentAttributes(a, b string) bool {
+	var decodedA, decodedB any
+	if err := json.Unmarshal([]byte(a), &decodedA); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(b), &decodedB); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(decodeAllNestedJSON(decodedA), decodeAllNestedJSON(decodedB))
+}
+
+func decodeAllNestedJSON(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			val[k] = decodeAllNestedJSON(child)
+		}
+	case []any:
+		for i, child := range val {
+			val[i] = decodeAllNestedJSON(child)
+		}
+	case string:
+		if nested, ok := decodeNestedJSON(val); ok {
+			return decodeAllNestedJSON(nested)
+		}
+	}
+	return v
+}
+
+// updateNestedJSON decodes the stringified JSON object at path, calls fn with it and stores the
+// re-encoded result. Nothing happens if there is no string at path.
+func updateNestedJSON(attributes map[string]any, fn func(map[string]any) error, path ...string) error {
+	parent := attributes
+	for _, key := range path[:len(path)-1] {
+		parent = nestedMap(parent, key)
+	}
+	key := path[len(path)-1]
+	s, ok := parent[key].(string)
+	if !ok || s == "" {
+		return nil
+	}
+
+	nested := map[string]any{}
+	if err := json.Unmarshal([]byte(s), &nested); err != nil {
+		return fmt.Errorf("%v is not a valid JSON object: %w", path, err)
+	}
+	if err := fn(nested); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(nested)
+	if err != nil {
+		return fmt.Errorf("could not encode %v: %w", path, err)
+	}
+	parent[key] = string(encoded)
+
+	return nil
+}
+
+// nestedMap returns v[key] if it is an object, otherwise an empty object.
+func nestedMap(v any, key string) map[string]any {
+	m, _ := v.(map[string]any)
+	nested, ok := m[key].(map[string]any)
+	if !ok {
+		return map[string]any{}
+	}
+	return nested
+}
+
+func copyMap(m map[string]any) map[string]any {
+	result := make(map[string]any, len(m))
+	for k, v := range m {
+		if nested, ok := v.(map[string]any); ok {
+			v = copyMap(nested)
+		}
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExtractAndInjectReferences(t *testing.T) {
+	inline := `{
+		"title": "dashboard",
+		"savedSearchId": "search",
+		"kibanaSavedObjectMeta": {
+			"searchSourceJSON": "{\"index\":\"pattern\",\"filter\":[{\"meta\":{\"index\":\"other-pattern\",\"key\":\"level\"}}]}"
+		},
+		"panelsJSON": "[{\"panelIndex\":\"1\",\"id\":\"vis\",\"type\":\"visualization\"}]",
+		"visState": "{\"type\":\"input_control_vis\",\"params\":{\"controls\":[{\"id\":\"1\",\"indexPattern\":\"pattern\"}]}}"
+	}`
+	attributes := map[string]any{}
+	if err := json.Unmarshal([]byte(inline), &attributes); err != nil {
+		t.Fatal(err)
+	}
+
+	extracted, refs, err := ExtractReferences(attributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantRefs := []Reference{
+		{ID: "pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"},
+		{ID: "other-pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.filter[0].meta.index", Type: "index-pattern"},
+		{ID: "vis", Name: "panel_0", Type: "visualization"},
+		{ID: "search", Name: "search_0", Type: "search"},
+		{ID: "pattern", Name: "control_0_index_pattern", Type: "index-pattern"},
+	}
+	if !reflect.DeepEqual(refs, wantRefs) {
+		t.Errorf("expected references %+v but got %+v", wantRefs, refs)
+	}
+	if err := ValidateReferences(extracted, refs); err != nil {
+		t.Errorf("extracted references must match the attributes: %v", err)
+	}
+
+	additional := Reference{ID: "unrelated", Type: "config"}
+	injected, remaining, err := InjectReferences(extracted, append(refs, additional))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(remaining, []Reference{additional}) {
+		t.Errorf("expected only the additional reference to remain but got %+v", remaining)
+	}
+
+	injectedJSON, err := json.Marshal(injected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EquivalentAttributes(inline, string(injectedJSON)) {
+		t.Errorf("expected attributes after round trip to be equivalent to %s but got %s", inline, injectedJSON)
+	}
+}
+
+func TestEquivalentAttributes(t *testing.T) {
+	testCases := []struct {
+		desc string
+		a    string
+		b    string
+		want bool
+	}{
+		{
+			desc: "must ignore key order of nested JSON",
+			a:    `{"visState": "{\"a\":1,\"b\":2}"}`,
+			b:    `{"visState": "{\"b\":2, \"a\":1}"}`,
+			want: true,
+		},
+		{
+			desc: "must detect different values of nested JSON",
+			a:    `{"visState": "{\"a\":1}"}`,
+			b:    `{"visState": "{\"a\":2}"}`,
+			want: false,
+		},
+		{
+			desc: "must not treat invalid JSON as equivalent",
+			a:    `{`,
+			b:    `{`,
+			want: false,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := EquivalentAttributes(tC.a, tC.b); got != tC.want {
+				t.Errorf("expected %v but got %v", tC.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+)
+
+// batchResult is the outcome of one item of a batch.
+type batchResult[R any] struct {
+	value R
+	err   error
+}
+
+// batchFunc sends the items of a batch of one tenant with a single request. It returns one result per item, in
+// the order of items, or an error if the whole request failed.
+type batchFunc[T, R any] func(ctx context.Context, tenant string, items []T) ([]batchResult[R], error)
+
+// batcher coalesces the items of concurrent calls of the same tenant arriving within window into a single request.
+// A batch is sent when the window has passed or it holds maxSize items, whichever comes first.
+type batcher[T, R any] struct {
+	window  time.Duration
+	maxSize int
+	send    batchFunc[T, R]
+
+	mu      sync.Mutex
+	pending map[string]*batch[T, R]
+}
+
+type batch[T, R any] struct {
+	// ctx is the context of the first call without its cancellation, so one caller giving up does not fail the others
+	ctx     context.Context
+	items   []T
+	results []batchResult[R]
+	once    sync.Once
+	done    chan struct{}
+}
+
+func newBatcher[T, R any](window time.Duration, maxSize int, send batchFunc[T, R]) *batcher[T, R] {
+	return &batcher[T, R]{
+		window:  window,
+		maxSize: maxSize,
+		send:    send,
+		pending: map[string]*batch[T, R]{},
+	}
+}
+
+// do adds item to the pending batch of the tenant and waits for its result.
+func (b *batcher[T, R]) do(ctx context.Context, tenant string, item T) (R, error) {
+	b.mu.Lock()
+	bt := b.pending[tenant]
+	if bt == nil {
+		bt = &batch[T, R]{ctx: context.WithoutCancel(ctx), done: make(chan struct{})}
+		b.pending[tenant] = bt
+		time.AfterFunc(b.window, func() { b.flush(tenant, bt) })
+	}
+	index := len(bt.items)
+	bt.items = append(bt.items, item)
+	if len(bt.items) >= b.maxSize {
+		delete(b.pending, tenant)
+		go b.flush(tenant, bt)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-bt.done:
+		result := bt.results[index]
+		return result.value, result.err
+	case <-ctx.Done():
+		var zero R
+		return zero, fmt.Errorf("waiting for batched request failed: %w", ctx.Err())
+	}
+}
+
+// flush sends the batch once, no items are added to it anymore.
+func (b *batcher[T, R]) flush(tenant string, bt *batch[T, R]) {
+	b.mu.Lock()
+	if b.pending[tenant] == bt {
+		delete(b.pending, tenant)
+	}
+	b.mu.Unlock()
+
+	bt.once.Do(func() {
+		results, err := b.send(bt.ctx, tenant, bt.items)
+		if err == nil && len(results) != len(bt.items) {
+			err = fmt.Errorf("batched request returned %d results for %d items", len(results), len(bt.items))
+		}
+		if err != nil {
+			results = make([]batchResult[R], len(bt.items))
+			for i := range results {
+				results[i].err = err
+			}
+		}
+		bt.results = results
+		close(bt.done)
+	})
+}
+
+// BatchReads enables read batching: concurrent calls of GetObject for the same tenant arriving within window are
+// fetched with a single _bulk_get request of at most maxSize objects.
+func (p *SavedObjectsProvider) BatchReads(window time.Duration, maxSize int) {
+	p.readBatcher = newBatcher(window, maxSize, p.bulkGetBatch)
+}
+
+func (p *SavedObjectsProvider) bulkGetBatch(ctx context.Context, tenant string, keys []ObjectKey) ([]batchResult[*SavedObjectOSD], error) {
+	objs, err := p.BulkGetObjects(ctx, tenant, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]batchResult[*SavedObjectOSD], len(objs))
+	for i := range objs {
+		if objs[i].Error != nil {
+			url := p.URL(fmt.Sprintf("/%s/%s", keys[i].Type, keys[i].ID))
+			results[i].err = apierror.FromStatus(http.MethodGet, url, objs[i].Error.StatusCode, objs[i].Error.Message)
+			continue
+		}
+		results[i].value = &objs[i].SavedObjectOSD
+	}
+
+	return results, nil
+}
@@ -0,0 +1,160 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+)
+
+func TestBatchReads(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		maxSize      int
+		reads        []SavedObjectOSD
+		wantRequests []string
+	}{
+		{
+			desc:    "must fetch concurrent reads with one request",
+			maxSize: 100,
+			reads: []SavedObjectOSD{
+				{Type: "dashboard", ID: "a"},
+				{Type: "visualization", ID: "b"},
+				{Type: "search", ID: "missing"},
+			},
+			wantRequests: []string{":3"},
+		},
+		{
+			desc:    "must send one request per tenant",
+			maxSize: 100,
+			reads: []SavedObjectOSD{
+				{Type: "dashboard", ID: "a", Tenant: "global"},
+				{Type: "dashboard", ID: "a", Tenant: "team"},
+				{Type: "visualization", ID: "b", Tenant: "team"},
+			},
+			wantRequests: []string{"global:1", "team:2"},
+		},
+		{
+			desc:    "must not exceed the maximum batch size",
+			maxSize: 2,
+			reads: []SavedObjectOSD{
+				{Type: "dashboard", ID: "a"},
+				{Type: "visualization", ID: "b"},
+				{Type: "visualization", ID: "c"},
+				{Type: "visualization", ID: "d"},
+			},
+			wantRequests: []string{":2", ":2"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var mu sync.Mutex
+			var requests []string
+
+			handler := http.NewServeMux()
+			handler.HandleFunc("/_dashboards/api/saved_objects/_bulk_get", func(w http.ResponseWriter, r *http.Request) {
+				var keys []ObjectKey
+				if err := json.NewDecoder(r.Body).Decode(&keys); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				mu.Lock()
+				requests = append(requests, fmt.Sprintf("%s:%d", r.Header.Get("securitytenant"), len(keys)))
+				mu.Unlock()
+
+				results := make([]map[string]any, 0, len(keys))
+				for _, key := range keys {
+					if key.ID == "missing" {
+						results = append(results, map[string]any{"type": key.Type, "id": key.ID, "error": map[string]any{"statusCode": 404, "message": "Not Found"}})
+						continue
+					}
+					title := key.ID + "@" + r.Header.Get("securitytenant")
+					results = append(results, map[string]any{"type": key.Type, "id": key.ID, "attributes": map[string]any{"title": title}})
+				}
+				json.NewEncoder(w).Encode(map[string]any{"saved_objects": results})
+			})
+			handler.HandleFunc("/_dashboards/api/saved_objects/", func(w http.ResponseWriter, _ *http.Request) {
+				t.Error("expected objects to be fetched with _bulk_get")
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
+			provider.BatchReads(50*time.Millisecond, tC.maxSize)
+
+			var wg sync.WaitGroup
+			for i := range tC.reads {
+				wg.Add(1)
+				go func(obj SavedObjectOSD) {
+					defer wg.Done()
+					resp, err := provider.GetObject(context.TODO(), &obj)
+					if obj.ID == "missing" {
+						if !errors.Is(err, apierror.ErrNotFound) {
+							t.Errorf("expected not found for %s but got %v", obj.ID, err)
+						}
+						return
+					}
+					if err != nil {
+						t.Errorf("could not get %s: %v", obj.ID, err)
+						return
+					}
+					want := fmt.Sprintf(`{"title":"%s@%s"}`, obj.ID, obj.Tenant)
+					if resp.ID != obj.ID || resp.Attributes != want {
+						t.Errorf("expected %s with %s but got %s with %s", obj.ID, want, resp.ID, resp.Attributes)
+					}
+				}(tC.reads[i])
+			}
+			wg.Wait()
+
+			sort.Strings(requests)
+			if fmt.Sprint(requests) != fmt.Sprint(tC.wantRequests) {
+				t.Errorf("expected requests %v but got %v", tC.wantRequests, requests)
+			}
+		})
+	}
+}
+
+func TestBatchReadsFailure(t *testing.T) {
+	handler := http.NewServeMux()
+	handler.HandleFunc("/_dashboards/api/saved_objects/_bulk_get", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
+	provider.BatchReads(10*time.Millisecond, 100)
+
+	_, err := provider.GetObject(context.TODO(), &SavedObjectOSD{Type: "dashboard", ID: "a"})
+	var apiErr *apierror.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 but got %v", err)
+	}
+}
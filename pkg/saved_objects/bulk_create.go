@@ -0,0 +1,101 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/httplog"
+)
+
+// bulkCreateObject is one object of a _bulk_create request, with the ID as sent to the API.
+type bulkCreateObject struct {
+	ObjectKey
+	SavedObjectPostPayload
+}
+
+type bulkCreateResponse struct {
+	SavedObjects []struct {
+		ObjectKey
+		Error *BulkError `json:"error,omitempty"`
+	} `json:"saved_objects"`
+}
+
+// BatchWrites enables write batching: concurrent calls of SaveObject for the same tenant arriving within window are
+// written with a single _bulk_create request of at most maxSize objects. Errors of single objects are returned by
+// the call which saved the object.
+func (p *SavedObjectsProvider) BatchWrites(window time.Duration, maxSize int) {
+	p.writeBatcher = newBatcher(window, maxSize, p.bulkCreateBatch)
+}
+
+func (p *SavedObjectsProvider) bulkCreateBatch(ctx context.Context, tenant string, objs []bulkCreateObject) ([]batchResult[struct{}], error) {
+	url := p.URL("/_bulk_create?overwrite=true")
+	jsonBytes, err := json.Marshal(objs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode saved objects as JSON: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(httplog.WithAttempts(ctx), http.MethodPost, url, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return nil, fmt.Errorf("could not create request for %v %v: %w", http.MethodPost, url, err)
+	}
+
+	req.Header.Set("osd-xsrf", "true")
+	req.Header.Set("Content-Type", "application/json")
+	setTenantHeader(req, tenant)
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("POST '%s' failed with err %w", req.URL.String(), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, apierror.FromResponse(res)
+	}
+
+	var body bulkCreateResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("request failed, cannot decode response body, err %w ", err)
+	}
+
+	// the results are matched by type and ID, so every error ends up at the object it belongs to
+	errs := make(map[ObjectKey]*BulkError, len(body.SavedObjects))
+	for _, obj := range body.SavedObjects {
+		errs[obj.ObjectKey] = obj.Error
+	}
+
+	results := make([]batchResult[struct{}], len(objs))
+	for i, obj := range objs {
+		objURL := p.URL(fmt.Sprintf("/%s/%s", obj.Type, obj.ID))
+		bulkErr, ok := errs[obj.ObjectKey]
+		switch {
+		case !ok:
+			results[i].err = fmt.Errorf("POST '%s' returned no result for %s %q", req.URL.String(), obj.Type, obj.ID)
+		case bulkErr != nil:
+			results[i].err = apierror.FromStatus(http.MethodPost, objURL, bulkErr.StatusCode, bulkErr.Message)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,126 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+)
+
+func TestBatchWrites(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/_dashboards/api/saved_objects/_bulk_create", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("overwrite") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var objs []bulkCreateObject
+		if err := json.NewDecoder(r.Body).Decode(&objs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		requests = append(requests, fmt.Sprintf("%s:%d", r.Header.Get("securitytenant"), len(objs)))
+		mu.Unlock()
+
+		// the results are returned in reverse order to check they are matched by type and ID
+		results := make([]map[string]any, 0, len(objs))
+		for i := len(objs) - 1; i >= 0; i-- {
+			obj := objs[i]
+			result := map[string]any{"type": obj.Type, "id": obj.ID}
+			if obj.ID == "conflict" {
+				result["error"] = map[string]any{"statusCode": 409, "message": "Saved object [dashboard/conflict] conflict"}
+			}
+			if obj.Attributes["title"] != obj.ID {
+				result["error"] = map[string]any{"statusCode": 400, "message": "attributes were not sent"}
+			}
+			results = append(results, result)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"saved_objects": results})
+	})
+	handler.HandleFunc("/_dashboards/api/saved_objects/", func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("expected objects to be written with _bulk_create")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
+	provider.BatchWrites(50*time.Millisecond, 100)
+
+	writes := []SavedObjectOSD{
+		{Type: "dashboard", ID: "a", Tenant: "global"},
+		{Type: "dashboard", ID: "conflict", Tenant: "global"},
+		{Type: "visualization", ID: "b", Tenant: "global"},
+		{Type: "visualization", ID: "b", Tenant: "team"},
+	}
+	var wg sync.WaitGroup
+	for i := range writes {
+		wg.Add(1)
+		go func(obj SavedObjectOSD) {
+			defer wg.Done()
+			obj.Attributes = map[string]any{"title": obj.ID}
+			err := provider.SaveObject(context.TODO(), &obj)
+			var conflictErr *apierror.ConflictError
+			if errors.As(err, &conflictErr) != (obj.ID == "conflict") {
+				t.Errorf("unexpected result for %s in tenant %s: %v", obj.ID, obj.Tenant, err)
+			}
+			if obj.ID != "conflict" && err != nil {
+				t.Errorf("could not save %s in tenant %s: %v", obj.ID, obj.Tenant, err)
+			}
+		}(writes[i])
+	}
+	wg.Wait()
+
+	sort.Strings(requests)
+	if want := "[global:3 team:1]"; fmt.Sprint(requests) != want {
+		t.Errorf("expected requests %v but got %v", want, requests)
+	}
+}
+
+func TestBatchWritesFailure(t *testing.T) {
+	handler := http.NewServeMux()
+	handler.HandleFunc("/_dashboards/api/saved_objects/_bulk_create", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
+	provider.BatchWrites(10*time.Millisecond, 100)
+
+	err := provider.SaveObject(context.TODO(), &SavedObjectOSD{Type: "dashboard", ID: "a"})
+	var apiErr *apierror.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413 but got %v", err)
+	}
+}
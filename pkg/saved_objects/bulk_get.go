@@ -0,0 +1,185 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/httplog"
+)
+
+// ObjectKey identifies a saved object.
+type ObjectKey struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// BulkGetResult is one object of a _bulk_get response. Error is set if the object could not be fetched,
+// e.g. with status code 404 if it does not exist.
+type BulkGetResult struct {
+	SavedObjectOSD
+	Error *BulkError `json:"error,omitempty"`
+}
+
+type BulkError struct {
+	StatusCode int    `json:"statusCode"`
+	Error      string `json:"error,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+type bulkGetResponse struct {
+	SavedObjects []BulkGetResult `json:"saved_objects"`
+}
+
+// BulkGetObjects fetches all objects of the tenant with a single request to _bulk_get. The results are in the
+// order of keys.
+func (p *SavedObjectsProvider) BulkGetObjects(ctx context.Context, tenant string, keys []ObjectKey) ([]BulkGetResult, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	url := p.URL("/_bulk_get")
+	jsonBytes, err := json.Marshal(keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode objects to get as JSON: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(httplog.WithAttempts(ctx), http.MethodPost, url, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return nil, fmt.Errorf("could not create request for %v %v: %w", http.MethodPost, url, err)
+	}
+
+	req.Header.Set("osd-xsrf", "true")
+	req.Header.Set("Content-Type", "application/json")
+	setTenantHeader(req, tenant)
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("POST '%s' failed: %w", req.URL.String(), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, apierror.FromResponse(res)
+	}
+
+	var body bulkGetResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("request failed, cannot decode response body, err %w ", err)
+	}
+	if len(body.SavedObjects) != len(keys) {
+		return nil, fmt.Errorf("POST '%s' returned %d objects but %d were requested", req.URL.String(), len(body.SavedObjects), len(keys))
+	}
+
+	return body.SavedObjects, nil
+}
+
+// FindMissingReferences looks up the targets of all references in the tenant with one _bulk_get request and
+// returns those which do not exist. With read batching, the targets are requested with the reads of other objects
+// instead. References are namespaced like by SaveObject, the missing ones are returned as given. Duplicate targets
+// are only requested once.
+func (p *SavedObjectsProvider) FindMissingReferences(ctx context.Context, tenant string, refs []Reference) ([]Reference, error) {
+	var keys []ObjectKey
+	seen := map[ObjectKey]bool{}
+	for _, ref := range refs {
+		key := referenceKey(p.IDNamespace, ref)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	var exists map[ObjectKey]bool
+	var err error
+	if p.readBatcher != nil {
+		exists, err = p.existingBatched(ctx, tenant, keys)
+	} else {
+		exists, err = p.existing(ctx, tenant, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []Reference
+	for _, ref := range refs {
+		if !exists[referenceKey(p.IDNamespace, ref)] {
+			missing = append(missing, ref)
+		}
+	}
+
+	return missing, nil
+}
+
+// existing returns which of the objects exist, fetched with a single _bulk_get request.
+func (p *SavedObjectsProvider) existing(ctx context.Context, tenant string, keys []ObjectKey) (map[ObjectKey]bool, error) {
+	results, err := p.BulkGetObjects(ctx, tenant, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	exists := map[ObjectKey]bool{}
+	for i, result := range results {
+		if result.Error == nil {
+			exists[keys[i]] = true
+			continue
+		}
+		if result.Error.StatusCode != http.StatusNotFound {
+			return nil, apierror.FromStatus(http.MethodGet, p.URL(fmt.Sprintf("/%s/%s", keys[i].Type, keys[i].ID)), result.Error.StatusCode, result.Error.Message)
+		}
+	}
+
+	return exists, nil
+}
+
+// existingBatched returns which of the objects exist, fetched concurrently through the read batcher.
+func (p *SavedObjectsProvider) existingBatched(ctx context.Context, tenant string, keys []ObjectKey) (map[ObjectKey]bool, error) {
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = p.readBatcher.do(ctx, tenant, keys[i])
+		}()
+	}
+	wg.Wait()
+
+	exists := map[ObjectKey]bool{}
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			exists[keys[i]] = true
+		case !errors.Is(err, apierror.ErrNotFound):
+			return nil, err
+		}
+	}
+
+	return exists, nil
+}
+
+// referenceKey returns the key of the object the reference points to on the server.
+func referenceKey(namespace *IDNamespace, ref Reference) ObjectKey {
+	ref = namespace.ApplyReference(ref)
+	return ObjectKey{Type: ref.Type, ID: ref.ID}
+}
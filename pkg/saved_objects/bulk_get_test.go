@@ -0,0 +1,183 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFindMissingReferences(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		wantErr     bool
+		namespace   *IDNamespace
+		refs        []Reference
+		want        []Reference
+		handlerFunc http.HandlerFunc
+	}{
+		{
+			desc: "must return references to objects which do not exist",
+			refs: []Reference{
+				{ID: "pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"},
+				{ID: "missing", Name: "search_0", Type: "search"},
+				{ID: "pattern", Name: "control_0_index_pattern", Type: "index-pattern"},
+			},
+			want: []Reference{{ID: "missing", Name: "search_0", Type: "search"}},
+			handlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				var keys []ObjectKey
+				if err := json.NewDecoder(r.Body).Decode(&keys); err != nil || len(keys) != 2 {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Write([]byte(`{"saved_objects":[
+					{"id":"pattern","type":"index-pattern","attributes":{"title":"logs-*"}},
+					{"id":"missing","type":"search","error":{"statusCode":404,"error":"Not Found","message":"Saved object [search/missing] not found"}}
+				]}`))
+			},
+		},
+		{
+			desc:      "must look up namespaced references in the namespace",
+			namespace: &IDNamespace{Prefix: "staging-"},
+			refs: []Reference{
+				{ID: "pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"},
+				{ID: "shared", Name: "kibanaSavedObjectMeta.searchSourceJSON.filter[0].meta.index", Type: "index-pattern", External: true},
+			},
+			want: []Reference{{ID: "shared", Name: "kibanaSavedObjectMeta.searchSourceJSON.filter[0].meta.index", Type: "index-pattern", External: true}},
+			handlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				var keys []ObjectKey
+				json.NewDecoder(r.Body).Decode(&keys)
+				if !reflect.DeepEqual(keys, []ObjectKey{{Type: "index-pattern", ID: "staging-pattern"}, {Type: "index-pattern", ID: "shared"}}) {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Write([]byte(`{"saved_objects":[
+					{"id":"staging-pattern","type":"index-pattern","attributes":{"title":"logs-*"}},
+					{"id":"shared","type":"index-pattern","error":{"statusCode":404,"error":"Not Found","message":"Saved object [index-pattern/shared] not found"}}
+				]}`))
+			},
+		},
+		{
+			desc:    "must fail if an object could not be fetched",
+			wantErr: true,
+			refs:    []Reference{{ID: "pattern", Type: "index-pattern"}},
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(`{"saved_objects":[{"id":"pattern","type":"index-pattern","error":{"statusCode":403,"message":"Forbidden"}}]}`))
+			},
+		},
+		{
+			desc:    "must fail when HTTP status not 200 OK",
+			wantErr: true,
+			refs:    []Reference{{ID: "pattern", Type: "index-pattern"}},
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+		{
+			desc: "must not send a request without references",
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			handler := http.NewServeMux()
+			handler.HandleFunc("/_dashboards/api/saved_objects/_bulk_get", tC.handlerFunc)
+
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
+			provider.IDNamespace = tC.namespace
+			missing, err := provider.FindMissingReferences(context.TODO(), "", tC.refs)
+			if tC.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(missing, tC.want) {
+				t.Errorf("expected %+v but got %+v", tC.want, missing)
+			}
+		})
+	}
+}
+
+func TestFindMissingReferencesBatched(t *testing.T) {
+	var requests atomic.Int32
+	handler := http.NewServeMux()
+	handler.HandleFunc("/api/saved_objects/_bulk_get", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var keys []ObjectKey
+		json.NewDecoder(r.Body).Decode(&keys)
+		var objects []string
+		for _, key := range keys {
+			if key.ID == "missing" {
+				objects = append(objects, `{"id":"missing","type":"search","error":{"statusCode":404,"message":"Not Found"}}`)
+				continue
+			}
+			objects = append(objects, fmt.Sprintf(`{"id":%q,"type":%q,"attributes":{}}`, key.ID, key.Type))
+		}
+		fmt.Fprintf(w, `{"saved_objects":[%s]}`, strings.Join(objects, ","))
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	provider := NewSavedObjectsProvider(srv.URL, http.DefaultClient, false)
+	// the batch is sent once it holds the targets of both objects
+	provider.BatchReads(time.Hour, 3)
+
+	refs := [][]Reference{
+		{{ID: "pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"}},
+		{{ID: "vis", Name: "panel_0", Type: "visualization"}, {ID: "missing", Name: "search_0", Type: "search"}},
+	}
+	missing := make([][]Reference, len(refs))
+	errs := make([]error, len(refs))
+	var wg sync.WaitGroup
+	for i := range refs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			missing[i], errs[i] = provider.FindMissingReferences(context.Background(), "", refs[i])
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if missing[0] != nil || !reflect.DeepEqual(missing[1], []Reference{{ID: "missing", Name: "search_0", Type: "search"}}) {
+		t.Errorf("expected only the search to be missing but got %v", missing)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected the targets to be fetched with one request but got %d", got)
+	}
+}
@@ -0,0 +1,181 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/cassette"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/cassettetest"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/sigv4"
+)
+
+var (
+	cassettePattern = SavedObjectOSD{Type: "index-pattern", ID: "cassette-pattern", SavedObjectPostPayload: SavedObjectPostPayload{
+		Attributes: map[string]any{"title": "cassette-*", "timeFieldName": "@timestamp"},
+	}}
+	cassetteVisualization = SavedObjectOSD{Type: "visualization", ID: "cassette-vis", SavedObjectPostPayload: SavedObjectPostPayload{
+		Attributes: map[string]any{
+			"title":                 "Cassette visualization",
+			"visState":              `{"title":"Cassette visualization","type":"metric","params":{},"aggs":[]}`,
+			"kibanaSavedObjectMeta": map[string]any{"searchSourceJSON": `{"indexRefName":"kibanaSavedObjectMeta.searchSourceJSON.index"}`},
+		},
+		References: []Reference{{ID: "cassette-pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"}},
+	}}
+	cassetteDashboard = SavedObjectOSD{Type: "dashboard", ID: "cassette-dashboard", SavedObjectPostPayload: SavedObjectPostPayload{
+		Attributes: map[string]any{
+			"title":      "Cassette dashboard",
+			"panelsJSON": `[{"panelIndex":"1","gridData":{"x":0,"y":0,"w":24,"h":15,"i":"1"},"panelRefName":"panel_0"}]`,
+		},
+		References: []Reference{{ID: "cassette-vis", Name: "panel_0", Type: "visualization"}},
+	}}
+)
+
+// signedTransport signs the requests to the cassette like the provider does for Amazon OpenSearch Service. The
+// signature is neither recorded nor matched, so signed requests are replayed from the same cassettes.
+func signedTransport(next http.RoundTripper) http.RoundTripper {
+	credentials := sigv4.CredentialsProviderFunc(func(context.Context) (sigv4.Credentials, error) {
+		return sigv4.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}, nil
+	})
+	return sigv4.NewTransport(&sigv4.Config{Service: "es", Region: "eu-central-1"}, credentials, next)
+}
+
+func TestCassetteSavedObjects(t *testing.T) {
+	for _, version := range cassette.Versions {
+		for _, signed := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/signed=%t", version, signed), func(t *testing.T) {
+				if mode, _ := cassette.ModeFromEnv(); signed && mode == cassette.ModeRecord {
+					t.Skip("cassettes are recorded without signature")
+				}
+				baseURL, client := cassettetest.Start(t, version, "saved_objects", cassette.Options{})
+				if signed {
+					client.Transport = signedTransport(client.Transport)
+				}
+				testCassetteSavedObjects(t, NewSavedObjectsProvider(baseURL, client, false))
+			})
+		}
+	}
+}
+
+func testCassetteSavedObjects(t *testing.T, p *SavedObjectsProvider) {
+	ctx := context.Background()
+
+	for _, obj := range []SavedObjectOSD{cassettePattern, cassetteVisualization, cassetteDashboard} {
+		if err := p.SaveObject(ctx, &obj); err != nil {
+			t.Fatalf("could not save %s: %v", obj.ID, err)
+		}
+	}
+
+	got, err := p.GetObject(ctx, &SavedObjectOSD{Type: "dashboard", ID: "cassette-dashboard"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.References, cassetteDashboard.References) {
+		t.Errorf("expected references %v but got %v", cassetteDashboard.References, got.References)
+	}
+	want, _ := cassetteDashboard.ToTF()
+	if !EquivalentAttributes(got.Attributes, want.Attributes) {
+		t.Errorf("expected attributes %s but got %s", want.Attributes, got.Attributes)
+	}
+
+	pattern, err := p.GetObject(ctx, &SavedObjectOSD{Type: "index-pattern", ID: "cassette-pattern"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pattern.Attributes != `{"timeFieldName":"@timestamp","title":"cassette-*"}` {
+		t.Errorf("expected the fields of the index pattern to be ignored but got %s", pattern.Attributes)
+	}
+
+	found, err := p.FindObjects(ctx, FindOptions{Types: []string{"dashboard"}, HasReference: &ObjectKey{Type: "visualization", ID: "cassette-vis"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != "cassette-dashboard" {
+		t.Errorf("expected to find the dashboard but got %v", found)
+	}
+
+	collected, err := p.CollectObjects(ctx, ObjectKey{Type: "dashboard", ID: "cassette-dashboard"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collected) != 2 || collected[0].ID != "cassette-dashboard" || collected[1].ID != "cassette-vis" {
+		t.Errorf("expected the dashboard and its visualization but got %v", collected)
+	}
+
+	missing, err := p.FindMissingReferences(ctx, "", []Reference{
+		{ID: "cassette-pattern", Type: "index-pattern"},
+		{ID: "cassette-missing", Name: "search_0", Type: "search"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(missing, []Reference{{ID: "cassette-missing", Name: "search_0", Type: "search"}}) {
+		t.Errorf("expected the search to be missing but got %v", missing)
+	}
+
+	for _, obj := range []SavedObjectOSD{cassetteDashboard, cassetteVisualization, cassettePattern} {
+		if err := p.DeleteObject(ctx, &obj); err != nil {
+			t.Fatalf("could not delete %s: %v", obj.ID, err)
+		}
+	}
+
+	if _, err := p.GetObject(ctx, &SavedObjectOSD{Type: "dashboard", ID: "cassette-dashboard"}); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", err)
+	}
+	if err := p.DeleteObject(ctx, &cassetteDashboard); err != nil {
+		t.Errorf("expected deleting a deleted object to succeed but got %v", err)
+	}
+}
+
+func TestCassetteBatches(t *testing.T) {
+	for _, version := range cassette.Versions {
+		t.Run(version, func(t *testing.T) {
+			baseURL, client := cassettetest.Start(t, version, "batches", cassette.Options{})
+			p := NewSavedObjectsProvider(baseURL, client, false)
+			// batches of one object are sent right away, so the requests are the same in every run
+			p.BatchReads(time.Hour, 1)
+			p.BatchWrites(time.Hour, 1)
+			ctx := context.Background()
+
+			if err := p.SaveObject(ctx, &cassettePattern); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := p.GetObject(ctx, &SavedObjectOSD{Type: "index-pattern", ID: "cassette-pattern"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Attributes != `{"timeFieldName":"@timestamp","title":"cassette-*"}` {
+				t.Errorf("unexpected attributes %s", got.Attributes)
+			}
+
+			if err := p.DeleteObject(ctx, &cassettePattern); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := p.GetObject(ctx, &SavedObjectOSD{Type: "index-pattern", ID: "cassette-pattern"}); !errors.Is(err, apierror.ErrNotFound) {
+				t.Errorf("expected ErrNotFound but got %v", err)
+			}
+		})
+	}
+}
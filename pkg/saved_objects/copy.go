@@ -0,0 +1,144 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+)
+
+// TitlePlaceholder is replaced with the title of the source object in CopyOptions.TitleTemplate.
+const TitlePlaceholder = "{{title}}"
+
+// CopiedTypes are the types of saved objects which are copied along with a dashboard. Other referenced
+// objects like index patterns are shared by the copies.
+var CopiedTypes = []string{"dashboard", "search", "visualization", "visualization-visbuilder"}
+
+// CopyOptions describe how the copy of a saved object is derived from its source.
+type CopyOptions struct {
+	// IDPrefix is prepended to the ID of the source
+	IDPrefix string
+	// TitleTemplate is the title of the copy, TitlePlaceholder is replaced with the title of the source
+	TitleTemplate string
+	// IndexPatternReplacements maps the IDs of index patterns referenced by the sources to the IDs referenced by the copies
+	IndexPatternReplacements map[string]string
+}
+
+// CopyID returns the ID of the copy of the object with the ID.
+func (o CopyOptions) CopyID(id string) string {
+	return o.IDPrefix + id
+}
+
+// CollectObjects returns the object root and all objects of the CopiedTypes it references directly or
+// indirectly, root first. Referenced objects which do not exist are skipped, if root does not exist nil is returned.
+func (p *SavedObjectsProvider) CollectObjects(ctx context.Context, root ObjectKey) ([]SavedObjectOSD, error) {
+	var result []SavedObjectOSD
+	queue := []ObjectKey{root}
+	seen := map[ObjectKey]bool{root: true}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+
+		resp, err := p.GetObject(ctx, &SavedObjectOSD{Type: key.Type, ID: key.ID})
+		if errors.Is(err, apierror.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		obj := SavedObjectOSD{Type: resp.Type, ID: resp.ID}
+		obj.References = resp.References
+		if err := json.Unmarshal([]byte(resp.Attributes), &obj.Attributes); err != nil {
+			return nil, fmt.Errorf("attributes of %s %q are not valid json: %w", key.Type, key.ID, err)
+		}
+		result = append(result, obj)
+
+		for _, ref := range obj.References {
+			refKey := ObjectKey{Type: ref.Type, ID: ref.ID}
+			if contains(CopiedTypes, ref.Type) && !seen[refKey] {
+				seen[refKey] = true
+				queue = append(queue, refKey)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// CopyObjects returns copies of the objects. References between the objects are rewritten to the copies and
+// references to index patterns according to the replacements.
+func CopyObjects(objs []SavedObjectOSD, opts CopyOptions) []SavedObjectOSD {
+	copied := make(map[ObjectKey]bool, len(objs))
+	for _, obj := range objs {
+		copied[ObjectKey{Type: obj.Type, ID: obj.ID}] = true
+	}
+
+	result := make([]SavedObjectOSD, 0, len(objs))
+	for _, obj := range objs {
+		c := SavedObjectOSD{Type: obj.Type, ID: opts.CopyID(obj.ID), Tenant: obj.Tenant}
+		c.Attributes = copyMap(obj.Attributes)
+		if title, ok := c.Attributes["title"].(string); ok && opts.TitleTemplate != "" {
+			c.Attributes["title"] = strings.ReplaceAll(opts.TitleTemplate, TitlePlaceholder, title)
+			setVisStateTitle(c.Attributes, c.Attributes["title"].(string))
+		}
+
+		c.References = make([]Reference, 0, len(obj.References))
+		for _, ref := range obj.References {
+			switch {
+			case copied[ObjectKey{Type: ref.Type, ID: ref.ID}]:
+				ref.ID = opts.CopyID(ref.ID)
+			case ref.Type == indexPatternType && opts.IndexPatternReplacements[ref.ID] != "":
+				ref.ID = opts.IndexPatternReplacements[ref.ID]
+			}
+			c.References = append(c.References, ref)
+		}
+
+		result = append(result, c)
+	}
+
+	return result
+}
+
+// setVisStateTitle sets the title which visualizations repeat in visState. visState is left as it is if it is not
+// valid JSON.
+func setVisStateTitle(attributes map[string]any, title string) {
+	raw, ok := attributes["visState"].(string)
+	if !ok {
+		return
+	}
+
+	var visState map[string]any
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&visState); err != nil {
+		return
+	}
+	if _, ok := visState["title"]; !ok {
+		return
+	}
+
+	visState["title"] = title
+	if encoded, err := json.Marshal(visState); err == nil {
+		attributes["visState"] = string(encoded)
+	}
+}
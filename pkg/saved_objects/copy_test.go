@@ -0,0 +1,106 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollectAndCopyObjects(t *testing.T) {
+	stored := map[string]SavedObjectOSD{
+		"dashboard/service": {Type: "dashboard", ID: "service", SavedObjectPostPayload: SavedObjectPostPayload{
+			Attributes: map[string]any{"title": "Service"},
+			References: []Reference{
+				{ID: "errors", Name: "panel_0", Type: "visualization"},
+				{ID: "latency", Name: "panel_1", Type: "visualization"},
+				{ID: "deleted", Name: "panel_2", Type: "visualization"},
+			},
+		}},
+		"visualization/errors": {Type: "visualization", ID: "errors", SavedObjectPostPayload: SavedObjectPostPayload{
+			Attributes: map[string]any{"title": "Errors", "visState": `{"title":"Errors","type":"line","params":{"size":10}}`},
+			References: []Reference{{ID: "error-search", Name: "search_0", Type: "search"}},
+		}},
+		"visualization/latency": {Type: "visualization", ID: "latency", SavedObjectPostPayload: SavedObjectPostPayload{
+			Attributes: map[string]any{"title": "Latency"},
+			References: []Reference{{ID: "logs", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"}},
+		}},
+		"search/error-search": {Type: "search", ID: "error-search", SavedObjectPostPayload: SavedObjectPostPayload{
+			Attributes: map[string]any{"title": "Error search"},
+			References: []Reference{{ID: "other", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"}},
+		}},
+	}
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/_dashboards/api/saved_objects/", func(w http.ResponseWriter, r *http.Request) {
+		obj, ok := stored[strings.TrimPrefix(r.URL.Path, "/_dashboards/api/saved_objects/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(obj)
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
+	sources, err := provider.CollectObjects(context.TODO(), ObjectKey{Type: "dashboard", ID: "service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copies := CopyObjects(sources, CopyOptions{
+		IDPrefix:                 "team-a-",
+		TitleTemplate:            TitlePlaceholder + " (team a)",
+		IndexPatternReplacements: map[string]string{"logs": "team-a-logs"},
+	})
+
+	want := []SavedObjectOSD{
+		{Type: "dashboard", ID: "team-a-service", SavedObjectPostPayload: SavedObjectPostPayload{
+			Attributes: map[string]any{"title": "Service (team a)"},
+			References: []Reference{
+				{ID: "team-a-errors", Name: "panel_0", Type: "visualization"},
+				{ID: "team-a-latency", Name: "panel_1", Type: "visualization"},
+				{ID: "deleted", Name: "panel_2", Type: "visualization"},
+			},
+		}},
+		{Type: "visualization", ID: "team-a-errors", SavedObjectPostPayload: SavedObjectPostPayload{
+			Attributes: map[string]any{"title": "Errors (team a)", "visState": `{"params":{"size":10},"title":"Errors (team a)","type":"line"}`},
+			References: []Reference{{ID: "team-a-error-search", Name: "search_0", Type: "search"}},
+		}},
+		{Type: "visualization", ID: "team-a-latency", SavedObjectPostPayload: SavedObjectPostPayload{
+			Attributes: map[string]any{"title": "Latency (team a)"},
+			References: []Reference{{ID: "team-a-logs", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"}},
+		}},
+		{Type: "search", ID: "team-a-error-search", SavedObjectPostPayload: SavedObjectPostPayload{
+			Attributes: map[string]any{"title": "Error search (team a)"},
+			References: []Reference{{ID: "other", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"}},
+		}},
+	}
+	if !reflect.DeepEqual(copies, want) {
+		t.Errorf("expected copies\n%+v\nbut got\n%+v", want, copies)
+	}
+	if sources[0].Attributes["title"] != "Service" {
+		t.Errorf("expected sources to be unchanged but got %+v", sources[0])
+	}
+}
@@ -0,0 +1,123 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/httplog"
+)
+
+const findPageSize = 100
+
+// ReferencingTypes are the saved object types which can hold references to other saved objects.
+var ReferencingTypes = []string{
+	"augment-vis",
+	"config",
+	"dashboard",
+	"index-pattern",
+	"query",
+	"search",
+	"visualization",
+	"visualization-visbuilder",
+}
+
+// FindOptions filters the objects returned by FindObjects.
+type FindOptions struct {
+	// Types to search, at least one is required
+	Types []string
+	// HasReference restricts the result to objects referencing this object
+	HasReference *ObjectKey
+	// Tenant to search in, the tenant of the user if empty
+	Tenant string
+}
+
+type findResponse struct {
+	Page         int              `json:"page"`
+	PerPage      int              `json:"per_page"`
+	Total        int              `json:"total"`
+	SavedObjects []SavedObjectOSD `json:"saved_objects"`
+}
+
+// FindObjects returns all objects matching the options. All pages of _find are fetched.
+func (p *SavedObjectsProvider) FindObjects(ctx context.Context, opts FindOptions) ([]SavedObjectOSD, error) {
+	if len(opts.Types) == 0 {
+		return nil, errors.New("at least one type is required to find saved objects")
+	}
+
+	query := url.Values{}
+	for _, t := range opts.Types {
+		query.Add("type", t)
+	}
+	if opts.HasReference != nil {
+		hasReference, err := json.Marshal(opts.HasReference)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode has_reference as JSON: %w", err)
+		}
+		query.Set("has_reference", string(hasReference))
+	}
+	query.Set("per_page", strconv.Itoa(findPageSize))
+
+	var result []SavedObjectOSD
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		body, err := p.findPage(ctx, query, opts.Tenant)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, body.SavedObjects...)
+
+		if len(body.SavedObjects) == 0 || len(result) >= body.Total {
+			return result, nil
+		}
+	}
+}
+
+func (p *SavedObjectsProvider) findPage(ctx context.Context, query url.Values, tenant string) (*findResponse, error) {
+	req, err := http.NewRequestWithContext(httplog.WithAttempts(ctx), http.MethodGet, p.URL("/_find?"+query.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not build request to GET _find %w", err)
+	}
+
+	req.Header.Set("osd-xsrf", "true")
+	req.Header.Set("Content-Type", "application/json")
+	setTenantHeader(req, tenant)
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET '%v' failed: %w", req.URL.String(), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, apierror.FromResponse(res)
+	}
+
+	body := &findResponse{}
+	if err := json.NewDecoder(res.Body).Decode(body); err != nil {
+		return nil, fmt.Errorf("request failed, cannot decode response body, err %w ", err)
+	}
+
+	return body, nil
+}
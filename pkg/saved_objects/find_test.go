@@ -0,0 +1,72 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestFindObjects(t *testing.T) {
+	const total = 150
+
+	var requests int
+	handler := http.NewServeMux()
+	handler.HandleFunc("/_dashboards/api/saved_objects/_find", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		query := r.URL.Query()
+		if got := query["type"]; len(got) != 2 || got[0] != "dashboard" || got[1] != "visualization" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if got := query.Get("has_reference"); got != `{"type":"index-pattern","id":"pattern"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		page, _ := strconv.Atoi(query.Get("page"))
+		perPage, _ := strconv.Atoi(query.Get("per_page"))
+		resp := findResponse{Page: page, PerPage: perPage, Total: total}
+		for i := (page - 1) * perPage; i < total && i < page*perPage; i++ {
+			resp.SavedObjects = append(resp.SavedObjects, SavedObjectOSD{Type: "dashboard", ID: fmt.Sprintf("dashboard-%d", i)})
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
+	objs, err := provider.FindObjects(context.TODO(), FindOptions{
+		Types:        []string{"dashboard", "visualization"},
+		HasReference: &ObjectKey{Type: "index-pattern", ID: "pattern"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != total {
+		t.Errorf("expected %d objects but got %d", total, len(objs))
+	}
+	if requests != 2 {
+		t.Errorf("expected all objects to be fetched with 2 requests but got %d", requests)
+	}
+}
@@ -0,0 +1,114 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MarkedTypes are the types of saved objects which are stamped with the ManagedMarker. Index patterns are
+// not included, because their title is the pattern itself.
+var MarkedTypes = []string{"dashboard", "query", "search", "visualization", "visualization-visbuilder"}
+
+// ManagedMarker makes objects managed by Terraform recognizable in the UI. The suffixes are appended to the
+// title and description when an object is saved and removed again when it is read.
+type ManagedMarker struct {
+	TitleSuffix       string
+	DescriptionSuffix string
+}
+
+// Stamp returns the attributes with the marker appended. The attributes passed in are not modified.
+// Descriptions are only stamped if the attributes contain one.
+func (m *ManagedMarker) Stamp(objType string, attributes map[string]any) map[string]any {
+	if m == nil || !contains(MarkedTypes, objType) {
+		return attributes
+	}
+
+	result := make(map[string]any, len(attributes))
+	for k, v := range attributes {
+		result[k] = v
+	}
+	appendSuffix(result, "title", m.TitleSuffix)
+	appendSuffix(result, "description", m.DescriptionSuffix)
+
+	return result
+}
+
+// Check returns an error if the title or description ends with the marker already. Strip could not tell such a
+// suffix from the marker, so it would be removed when the object is read.
+func (m *ManagedMarker) Check(objType string, attributes map[string]any) error {
+	if m == nil || !contains(MarkedTypes, objType) {
+		return nil
+	}
+
+	for _, key := range []string{"title", "description"} {
+		suffix := m.TitleSuffix
+		if key == "description" {
+			suffix = m.DescriptionSuffix
+		}
+		if v, ok := attributes[key].(string); ok && suffix != "" && strings.HasSuffix(v, suffix) {
+			return fmt.Errorf("%s %q must not end with the managed marker %q, it is appended by the provider", key, v, suffix)
+		}
+	}
+
+	return nil
+}
+
+// Strip removes the marker from the attributes in place.
+func (m *ManagedMarker) Strip(objType string, attributes map[string]any) {
+	if m == nil || !contains(MarkedTypes, objType) {
+		return
+	}
+
+	trimSuffix(attributes, "title", m.TitleSuffix)
+	trimSuffix(attributes, "description", m.DescriptionSuffix)
+}
+
+// IsMarked reports whether the title or description of the attributes carries the marker.
+func (m *ManagedMarker) IsMarked(objType string, attributes map[string]any) bool {
+	if m == nil || !contains(MarkedTypes, objType) {
+		return false
+	}
+
+	title, _ := attributes["title"].(string)
+	description, _ := attributes["description"].(string)
+
+	return (m.TitleSuffix != "" && strings.HasSuffix(title, m.TitleSuffix)) ||
+		(m.DescriptionSuffix != "" && strings.HasSuffix(description, m.DescriptionSuffix))
+}
+
+func appendSuffix(attributes map[string]any, key, suffix string) {
+	if v, ok := attributes[key].(string); ok && suffix != "" {
+		attributes[key] = v + suffix
+	}
+}
+
+func trimSuffix(attributes map[string]any, key, suffix string) {
+	if v, ok := attributes[key].(string); ok && suffix != "" {
+		attributes[key] = strings.TrimSuffix(v, suffix)
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
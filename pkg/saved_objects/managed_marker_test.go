@@ -0,0 +1,115 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestManagedMarker(t *testing.T) {
+	var stored SavedObjectPostPayload
+	handler := http.NewServeMux()
+	handler.HandleFunc("/_dashboards/api/saved_objects/dashboard/mock-dashboard", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			json.NewDecoder(r.Body).Decode(&stored)
+			return
+		}
+		json.NewEncoder(w).Encode(SavedObjectOSD{Type: "dashboard", ID: "mock-dashboard", SavedObjectPostPayload: stored})
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
+	provider.ManagedMarker = &ManagedMarker{TitleSuffix: " [terraform]", DescriptionSuffix: " (managed)"}
+
+	attributes := map[string]any{"title": "dashboard", "description": "errors"}
+	obj := &SavedObjectOSD{Type: "dashboard", ID: "mock-dashboard", SavedObjectPostPayload: SavedObjectPostPayload{Attributes: attributes}}
+	if err := provider.SaveObject(context.TODO(), obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Attributes["title"] != "dashboard [terraform]" || stored.Attributes["description"] != "errors (managed)" {
+		t.Errorf("expected stamped title and description but got %+v", stored.Attributes)
+	}
+	if attributes["title"] != "dashboard" {
+		t.Errorf("expected attributes of the caller to be unchanged but got %+v", attributes)
+	}
+	if !provider.ManagedMarker.IsMarked("dashboard", stored.Attributes) {
+		t.Error("expected stored object to be marked")
+	}
+
+	read, err := provider.GetObject(context.TODO(), obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"description":"errors","title":"dashboard"}`; read.Attributes != want {
+		t.Errorf("expected marker to be stripped on read, want %s but got %s", want, read.Attributes)
+	}
+}
+
+func TestManagedMarkerIgnoresIndexPatterns(t *testing.T) {
+	marker := &ManagedMarker{TitleSuffix: " [terraform]"}
+	attributes := map[string]any{"title": "logs-*"}
+	if got := marker.Stamp("index-pattern", attributes); got["title"] != "logs-*" {
+		t.Errorf("expected index pattern title to be unchanged but got %v", got["title"])
+	}
+}
+
+func TestManagedMarkerCheck(t *testing.T) {
+	marker := &ManagedMarker{TitleSuffix: " [terraform]", DescriptionSuffix: " (managed)"}
+	testCases := []struct {
+		desc       string
+		objType    string
+		attributes map[string]any
+		wantErr    bool
+	}{
+		{
+			desc:       "must accept attributes without marker",
+			objType:    "dashboard",
+			attributes: map[string]any{"title": "Errors", "description": "All errors"},
+		},
+		{
+			desc:       "must reject titles ending with the marker",
+			objType:    "dashboard",
+			attributes: map[string]any{"title": "Errors [terraform]"},
+			wantErr:    true,
+		},
+		{
+			desc:       "must reject descriptions ending with the marker",
+			objType:    "visualization",
+			attributes: map[string]any{"title": "Errors", "description": "All errors (managed)"},
+			wantErr:    true,
+		},
+		{
+			desc:       "must ignore types which are not marked",
+			objType:    "index-pattern",
+			attributes: map[string]any{"title": "logs-* [terraform]"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if err := marker.Check(tC.objType, tC.attributes); (err != nil) != tC.wantErr {
+				t.Errorf("expected error %v but got %v", tC.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import "strings"
+
+// IDNamespace is applied to the IDs of all saved objects, so the same configuration can be deployed several
+// times into one OpenSearch Dashboards instance or tenant. References are namespaced as well, unless they are
+// marked as external.
+type IDNamespace struct {
+	Prefix string
+	Suffix string
+}
+
+// Apply returns the ID with prefix and suffix.
+func (n *IDNamespace) Apply(id string) string {
+	if n == nil {
+		return id
+	}
+	return n.Prefix + id + n.Suffix
+}
+
+// ApplyKey returns the key with the namespaced ID.
+func (n *IDNamespace) ApplyKey(key ObjectKey) ObjectKey {
+	return ObjectKey{Type: key.Type, ID: n.Apply(key.ID)}
+}
+
+// Contains reports whether the ID has the prefix and the suffix of the namespace.
+func (n *IDNamespace) Contains(id string) bool {
+	if n == nil {
+		return false
+	}
+	return len(id) >= len(n.Prefix)+len(n.Suffix) && strings.HasPrefix(id, n.Prefix) && strings.HasSuffix(id, n.Suffix)
+}
+
+// Strip removes prefix and suffix from an ID in the namespace. Other IDs are returned unchanged.
+func (n *IDNamespace) Strip(id string) string {
+	if !n.Contains(id) {
+		return id
+	}
+	return id[len(n.Prefix) : len(id)-len(n.Suffix)]
+}
+
+// ApplyReference returns the reference pointing to the namespaced object. External references are returned
+// unchanged.
+func (n *IDNamespace) ApplyReference(ref Reference) Reference {
+	if !ref.External {
+		ref.ID = n.Apply(ref.ID)
+	}
+	return ref
+}
+
+// StripReference reverses ApplyReference. References configured as external keep their ID and stay external, even
+// if the ID looks namespaced. Other references to objects outside of the namespace are marked as external.
+func (n *IDNamespace) StripReference(ref Reference, configured []Reference) Reference {
+	if n == nil {
+		return ref
+	}
+	for _, c := range configured {
+		if c.External && c.ID == ref.ID && c.Name == ref.Name && c.Type == ref.Type {
+			return c
+		}
+	}
+	ref.External = !n.Contains(ref.ID)
+	ref.ID = n.Strip(ref.ID)
+	return ref
+}
@@ -0,0 +1,124 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestIDNamespace(t *testing.T) {
+	stored := map[ObjectKey]SavedObjectPostPayload{}
+	var deleted []string
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/_dashboards/api/saved_objects/_bulk_get", func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("expected references to be namespaced without looking up their targets")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	handler.HandleFunc("/_dashboards/api/saved_objects/search/", func(w http.ResponseWriter, r *http.Request) {
+		key := ObjectKey{Type: "search", ID: r.URL.Path[len("/_dashboards/api/saved_objects/search/"):]}
+		switch r.Method {
+		case http.MethodPost:
+			var payload SavedObjectPostPayload
+			json.NewDecoder(r.Body).Decode(&payload)
+			stored[key] = payload
+		case http.MethodDelete:
+			deleted = append(deleted, key.ID)
+		default:
+			json.NewEncoder(w).Encode(SavedObjectOSD{Type: key.Type, ID: key.ID, SavedObjectPostPayload: stored[key]})
+		}
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
+	provider.IDNamespace = &IDNamespace{Prefix: "staging-"}
+
+	refs := []Reference{
+		{ID: "pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"},
+		{ID: "shared-pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.filter[0].meta.index", Type: "index-pattern", External: true},
+		{ID: "not-yet-created", Name: "search_0", Type: "search"},
+		{ID: "staging-shared", Name: "kibanaSavedObjectMeta.searchSourceJSON.filter[1].meta.index", Type: "index-pattern", External: true},
+	}
+	obj := &SavedObjectOSD{Type: "search", ID: "errors", SavedObjectPostPayload: SavedObjectPostPayload{
+		Attributes: map[string]any{"title": "errors"},
+		References: refs,
+	}}
+	if err := provider.SaveObject(context.TODO(), obj); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, ok := stored[ObjectKey{Type: "search", ID: "staging-errors"}]
+	if !ok {
+		t.Fatalf("expected object to be saved with namespaced ID but got %+v", stored)
+	}
+	wantRefs := []Reference{
+		{ID: "staging-pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"},
+		{ID: "shared-pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.filter[0].meta.index", Type: "index-pattern"},
+		{ID: "staging-not-yet-created", Name: "search_0", Type: "search"},
+		{ID: "staging-shared", Name: "kibanaSavedObjectMeta.searchSourceJSON.filter[1].meta.index", Type: "index-pattern"},
+	}
+	if !reflect.DeepEqual(payload.References, wantRefs) {
+		t.Errorf("expected references %+v but got %+v", wantRefs, payload.References)
+	}
+	if obj.ID != "errors" || obj.References[0].ID != "pattern" {
+		t.Errorf("expected object of the caller to be unchanged but got %+v", obj)
+	}
+
+	read, err := provider.GetObject(context.TODO(), obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.ID != "errors" || !reflect.DeepEqual(read.References, refs) {
+		t.Errorf("expected namespace to be removed on read but got %+v", read)
+	}
+
+	if err := provider.DeleteObject(context.TODO(), obj); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(deleted, []string{"staging-errors"}) {
+		t.Errorf("expected namespaced object to be deleted but got %v", deleted)
+	}
+}
+
+func TestIDNamespaceStrip(t *testing.T) {
+	namespace := &IDNamespace{Prefix: "dev-", Suffix: "-eu"}
+	testCases := []struct {
+		id   string
+		want string
+	}{
+		{id: "dev-errors-eu", want: "errors"},
+		{id: "dev-errors", want: "dev-errors"},
+		{id: "dev-eu", want: "dev-eu"},
+	}
+	for _, tC := range testCases {
+		if got := namespace.Strip(tC.id); got != tC.want {
+			t.Errorf("expected %q to be stripped to %q but got %q", tC.id, tC.want, got)
+		}
+	}
+
+	var none *IDNamespace
+	if got := none.Apply("errors"); got != "errors" {
+		t.Errorf("expected nil namespace to keep the ID but got %q", got)
+	}
+}
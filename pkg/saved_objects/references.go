@@ -0,0 +1,136 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const refNameSuffix = "RefName"
+
+// RefNameUsage is a reference name used inside the attributes of a saved object, e.g. the value of
+// kibanaSavedObjectMeta.searchSourceJSON.indexRefName.
+type RefNameUsage struct {
+	Name string
+	// Path is the attribute path of the usage. Stringified JSON like searchSourceJSON is traversed
+	// as if it was nested.
+	Path string
+}
+
+// FindRefNames returns all reference names used in the attributes, sorted by their path. Every key
+// ending with RefName (indexRefName, panelRefName, savedSearchRefName, ...) is considered a usage.
+func FindRefNames(attributes map[string]any) []RefNameUsage {
+	var usages []RefNameUsage
+	walkAttributes("", attributes, func(path, key string, value any) {
+		if !strings.HasSuffix(key, refNameSuffix) {
+			return
+		}
+		if name, ok := value.(string); ok && name != "" {
+			usages = append(usages, RefNameUsage{Name: name, Path: path})
+		}
+	})
+
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Path < usages[j].Path })
+
+	return usages
+}
+
+// ValidateReferences checks that every reference name used in the attributes has exactly one matching
+// reference. Named references which are not used are reported by UnusedReferences instead, OpenSearch Dashboards
+// keeps them. References without a name are not checked.
+func ValidateReferences(attributes map[string]any, refs []Reference) error {
+	var errs []error
+	defined := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		if ref.Name == "" {
+			continue
+		}
+		if defined[ref.Name] {
+			errs = append(errs, fmt.Errorf("references: reference name %q is defined more than once", ref.Name))
+		}
+		defined[ref.Name] = true
+	}
+
+	for _, usage := range FindRefNames(attributes) {
+		if !defined[usage.Name] {
+			errs = append(errs, fmt.Errorf("attributes.%s: reference %q is used but there is no reference with this name", usage.Path, usage.Name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// UnusedReferences returns the named references whose names are not used in the attributes.
+func UnusedReferences(attributes map[string]any, refs []Reference) []Reference {
+	used := map[string]bool{}
+	for _, usage := range FindRefNames(attributes) {
+		used[usage.Name] = true
+	}
+
+	var unused []Reference
+	for _, ref := range refs {
+		if ref.Name != "" && !used[ref.Name] {
+			unused = append(unused, ref)
+		}
+	}
+
+	return unused
+}
+
+// walkAttributes calls fn for every key of every object nested in v. String values which contain a JSON
+// object or array are decoded and traversed as well, because OpenSearch Dashboards stores e.g.
+// searchSourceJSON, panelsJSON and visState as stringified JSON.
+func walkAttributes(path string, v any, fn func(path, key string, value any)) {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, child := range val {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			fn(childPath, key, child)
+			walkAttributes(childPath, child, fn)
+		}
+	case []any:
+		for i, child := range val {
+			walkAttributes(fmt.Sprintf("%s[%d]", path, i), child, fn)
+		}
+	case string:
+		if nested, ok := decodeNestedJSON(val); ok {
+			walkAttributes(path, nested, fn)
+		}
+	}
+}
+
+// decodeNestedJSON decodes s if it is a stringified JSON object or array.
+func decodeNestedJSON(s string) (any, bool) {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+
+	var nested any
+	if err := json.Unmarshal([]byte(trimmed), &nested); err != nil {
+		return nil, false
+	}
+
+	return nested, true
+}
@@ -0,0 +1,126 @@
+package saved_objects
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const dashboardAttributes = `{
+	"title": "dashboard",
+	"kibanaSavedObjectMeta": {
+		"searchSourceJSON": "{\"filter\":[{\"meta\":{\"indexRefName\":\"kibanaSavedObjectMeta.searchSourceJSON.filter[0].meta.index\"}}]}"
+	},
+	"panelsJSON": "[{\"panelIndex\":\"1\",\"panelRefName\":\"panel_0\"},{\"panelIndex\":\"2\",\"panelRefName\":\"panel_1\"}]"
+}`
+
+func TestFindRefNames(t *testing.T) {
+	attributes := map[string]any{}
+	if err := json.Unmarshal([]byte(dashboardAttributes), &attributes); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []RefNameUsage{
+		{Name: "kibanaSavedObjectMeta.searchSourceJSON.filter[0].meta.index", Path: "kibanaSavedObjectMeta.searchSourceJSON.filter[0].meta.indexRefName"},
+		{Name: "panel_0", Path: "panelsJSON[0].panelRefName"},
+		{Name: "panel_1", Path: "panelsJSON[1].panelRefName"},
+	}
+	if got := FindRefNames(attributes); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v but got %+v", want, got)
+	}
+}
+
+func TestValidateReferences(t *testing.T) {
+	allRefs := []Reference{
+		{ID: "pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.filter[0].meta.index", Type: "index-pattern"},
+		{ID: "vis-a", Name: "panel_0", Type: "visualization"},
+		{ID: "vis-b", Name: "panel_1", Type: "visualization"},
+	}
+
+	testCases := []struct {
+		desc     string
+		refs     []Reference
+		wantErrs []string
+	}{
+		{
+			desc: "must accept matching references",
+			refs: allRefs,
+		},
+		{
+			desc:     "must report missing references with their path",
+			refs:     allRefs[:2],
+			wantErrs: []string{`attributes.panelsJSON[1].panelRefName: reference "panel_1" is used`},
+		},
+		{
+			desc: "must accept unused references",
+			refs: append(append([]Reference{}, allRefs...), Reference{ID: "search", Name: "search_0", Type: "search"}),
+		},
+		{
+			desc:     "must report duplicate reference names",
+			refs:     append(append([]Reference{}, allRefs...), Reference{ID: "vis-c", Name: "panel_1", Type: "visualization"}),
+			wantErrs: []string{`reference name "panel_1" is defined more than once`},
+		},
+		{
+			desc: "must ignore references without name",
+			refs: append(append([]Reference{}, allRefs...), Reference{ID: "search", Type: "search"}),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			attributes := map[string]any{}
+			if err := json.Unmarshal([]byte(dashboardAttributes), &attributes); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ValidateReferences(attributes, tC.refs)
+			if len(tC.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("expected no error but got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error but got none")
+			}
+			for _, want := range tC.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error containing %q but got %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestUnusedReferences(t *testing.T) {
+	attributes := map[string]any{}
+	if err := json.Unmarshal([]byte(dashboardAttributes), &attributes); err != nil {
+		t.Fatal(err)
+	}
+	refs := []Reference{
+		{ID: "vis-a", Name: "panel_0", Type: "visualization"},
+		{ID: "search", Name: "search_0", Type: "search"},
+		{ID: "other", Type: "search"},
+	}
+
+	want := []Reference{{ID: "search", Name: "search_0", Type: "search"}}
+	if got := UnusedReferences(attributes, refs); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v but got %v", want, got)
+	}
+}
@@ -21,18 +21,38 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/httplog"
 )
 
 const indexPatternType = "index-pattern"
 
+// Client is the interface of the saved objects API implemented by SavedObjectsProvider.
+type Client interface {
+	GetObject(ctx context.Context, obj *SavedObjectOSD) (*SavedObjectTF, error)
+	SaveObject(ctx context.Context, obj *SavedObjectOSD) error
+	DeleteObject(ctx context.Context, obj *SavedObjectOSD) error
+	BulkGetObjects(ctx context.Context, tenant string, keys []ObjectKey) ([]BulkGetResult, error)
+	FindMissingReferences(ctx context.Context, tenant string, refs []Reference) ([]Reference, error)
+	FindObjects(ctx context.Context, opts FindOptions) ([]SavedObjectOSD, error)
+	CollectObjects(ctx context.Context, root ObjectKey) ([]SavedObjectOSD, error)
+}
+
+var _ Client = &SavedObjectsProvider{}
+
 type SavedObjectsProvider struct {
 	BaseUrl                string
 	httpClient             *http.Client
 	SyncIndexPatternFields bool
+	// ManagedMarker is stamped on all saved objects written and stripped from all objects read, if set
+	ManagedMarker *ManagedMarker
+	// IDNamespace is applied to the IDs of all objects written, read and deleted, if set
+	IDNamespace *IDNamespace
+
+	readBatcher  *batcher[ObjectKey, *SavedObjectOSD]
+	writeBatcher *batcher[bulkCreateObject, struct{}]
 }
 
 func NewSavedObjectsProvider(baseUrl string, client *http.Client, syncIndexPatternFields bool) *SavedObjectsProvider {
@@ -43,121 +63,182 @@ func NewSavedObjectsProvider(baseUrl string, client *http.Client, syncIndexPatte
 	}
 }
 
-func (p *SavedObjectsProvider) GetObject(ctx context.Context, obj *SavedObjectOSD) (*SavedObjectTF, diag.Diagnostics) {
-	url := fmt.Sprintf("/%s/%s", obj.Type, obj.ID)
+// GetObject returns the object. If it does not exist, an error matching apierror.ErrNotFound is returned. With
+// read batching enabled, the object is fetched together with concurrent reads of the same tenant.
+func (p *SavedObjectsProvider) GetObject(ctx context.Context, obj *SavedObjectOSD) (*SavedObjectTF, error) {
+	key := ObjectKey{Type: obj.Type, ID: p.IDNamespace.Apply(obj.ID)}
+
+	var raw *SavedObjectOSD
+	var err error
+	if p.readBatcher != nil {
+		raw, err = p.readBatcher.do(ctx, obj.Tenant, key)
+	} else {
+		raw, err = p.getObject(ctx, obj.Tenant, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toTF(raw, obj.References)
+}
+
+// getObject fetches the object with a single GET request.
+func (p *SavedObjectsProvider) getObject(ctx context.Context, tenant string, key ObjectKey) (*SavedObjectOSD, error) {
+	url := fmt.Sprintf("/%s/%s", key.Type, key.ID)
 	// build request
 	req, err := http.NewRequestWithContext(
-		ctx,
+		httplog.WithAttempts(ctx),
 		http.MethodGet,
 		p.URL(url),
 		nil,
 	)
-	req.Header.Set("osd-xsrf", "true")
-	req.Header.Set("Content-Type", "application/json")
-
 	if err != nil {
-		return nil, diag.FromErr(fmt.Errorf("could not build request to GET %v %w", url, err))
+		return nil, fmt.Errorf("could not build request to GET %v %w", url, err)
 	}
 
+	req.Header.Set("osd-xsrf", "true")
+	req.Header.Set("Content-Type", "application/json")
+	setTenantHeader(req, tenant)
+
 	res, err := p.httpClient.Do(req)
 	if err != nil {
-		return nil, diag.FromErr(fmt.Errorf("GET '%v' failed: %w", req.URL.String(), err))
-	}
-	if res.StatusCode == http.StatusNotFound {
-		return nil, nil
+		return nil, fmt.Errorf("GET '%v' failed: %w", req.URL.String(), err)
 	}
+	defer res.Body.Close()
+
 	if res.StatusCode != http.StatusOK {
-		response, bodyReadErr := io.ReadAll(res.Body)
-		if bodyReadErr != nil {
-			return nil, diag.FromErr(fmt.Errorf("GET '%v' failed with status %d", req.URL.String(), res.StatusCode))
-		}
-		return nil, diag.FromErr(fmt.Errorf("GET '%v' failed with status %d\nresponse_body: %v", req.URL.String(), res.StatusCode, string(response)))
+		return nil, apierror.FromResponse(res)
 	}
 	// parse result
-	obj = &SavedObjectOSD{}
+	obj := &SavedObjectOSD{}
 	err = json.NewDecoder(res.Body).Decode(obj)
 	if err != nil {
-		return nil, diag.FromErr(fmt.Errorf("request failed, cannot decode response body, err %w ", err))
+		return nil, fmt.Errorf("request failed, cannot decode response body, err %w ", err)
 	}
 
+	return obj, nil
+}
+
+// toTF converts an object as returned by the API to the object as configured in Terraform. The configured references
+// tell which references were not namespaced.
+func (p *SavedObjectsProvider) toTF(obj *SavedObjectOSD, configured []Reference) (*SavedObjectTF, error) {
 	if obj.Type == indexPatternType && !p.SyncIndexPatternFields {
 		delete(obj.Attributes, "fields")
 	}
+	p.ManagedMarker.Strip(obj.Type, obj.Attributes)
 
 	stringifiedAttributes, err := json.Marshal(obj.Attributes)
 	if err != nil {
-		return nil, diag.FromErr(fmt.Errorf("request failed, cannot stringify attibutes from response body, err %w ", err))
+		return nil, fmt.Errorf("request failed, cannot stringify attibutes from response body, err %w ", err)
 	}
 
 	result := &SavedObjectTF{
 		Type:       obj.Type,
-		ID:         obj.ID,
+		ID:         p.IDNamespace.Strip(obj.ID),
 		Attributes: string(stringifiedAttributes),
 		References: obj.References,
 	}
+	for i := range result.References {
+		result.References[i] = p.IDNamespace.StripReference(result.References[i], configured)
+	}
 
 	return result, nil
 }
 
-func (p *SavedObjectsProvider) SaveObject(ctx context.Context, obj *SavedObjectOSD) diag.Diagnostics {
-	url := p.URL(fmt.Sprintf("/%s/%s%s", obj.Type, obj.ID, "?overwrite=true"))
+// SaveObject creates or overwrites the object, so updating an object which was deleted outside of terraform recreates it.
+// With write batching enabled, the object is written together with concurrent writes of the same tenant.
+func (p *SavedObjectsProvider) SaveObject(ctx context.Context, obj *SavedObjectOSD) error {
+	payload := obj.SavedObjectPostPayload
+	if err := p.ManagedMarker.Check(obj.Type, payload.Attributes); err != nil {
+		return fmt.Errorf("could not save %s %q: %w", obj.Type, obj.ID, err)
+	}
+	payload.Attributes = p.ManagedMarker.Stamp(obj.Type, payload.Attributes)
+	if p.IDNamespace != nil && len(payload.References) > 0 {
+		refs := make([]Reference, len(payload.References))
+		for i, ref := range payload.References {
+			refs[i] = p.IDNamespace.ApplyReference(ref)
+		}
+		payload.References = refs
+	}
+
+	key := ObjectKey{Type: obj.Type, ID: p.IDNamespace.Apply(obj.ID)}
+	if p.writeBatcher != nil {
+		_, err := p.writeBatcher.do(ctx, obj.Tenant, bulkCreateObject{ObjectKey: key, SavedObjectPostPayload: payload})
+		return err
+	}
+
+	return p.saveObject(ctx, obj.Tenant, key, payload)
+}
+
+// saveObject writes the object with a single POST request.
+func (p *SavedObjectsProvider) saveObject(ctx context.Context, tenant string, key ObjectKey, payload SavedObjectPostPayload) error {
+	url := p.URL(fmt.Sprintf("/%s/%s%s", key.Type, key.ID, "?overwrite=true"))
 
-	jsonBytes, err := json.Marshal(obj.SavedObjectPostPayload)
+	jsonBytes, err := json.Marshal(payload)
 	if err != nil {
-		return diag.Errorf("failed to encode saved object as JSON: %v", err)
+		return fmt.Errorf("failed to encode saved object as JSON: %w", err)
 	}
-	payload := bytes.NewBuffer(jsonBytes)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, payload)
+	req, err := http.NewRequestWithContext(httplog.WithAttempts(ctx), http.MethodPost, url, bytes.NewBuffer(jsonBytes))
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("could not create request for %v %v: %w", http.MethodPost, url, err))
+		return fmt.Errorf("could not create request for %v %v: %w", http.MethodPost, url, err)
 	}
 
 	req.Header.Set("osd-xsrf", "true")
 	req.Header.Set("Content-Type", "application/json")
+	setTenantHeader(req, tenant)
 
 	res, err := p.httpClient.Do(req)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("POST '%s' with body '%v' failed with err %w", req.URL.String(), string(jsonBytes), err))
+		return fmt.Errorf("POST '%s' failed: %w", req.URL.String(), err)
 	}
+	defer res.Body.Close()
+
 	if res.StatusCode != http.StatusOK {
-		rawBody, bodyReadErr := io.ReadAll(res.Body)
-		if bodyReadErr != nil {
-			return diag.FromErr(fmt.Errorf("POST '%s' failed with status %d\nrequest_body: %s\nresponse body could not be parsed %w", req.URL.String(), res.StatusCode, string(jsonBytes), bodyReadErr))
-		}
-		return diag.FromErr(fmt.Errorf("POST '%s' failed with status %d\nrequest_body: %s\nresponse_body: %s", req.URL.String(), res.StatusCode, string(jsonBytes), string(rawBody)))
+		return apierror.FromResponse(res)
 	}
 	return nil
 }
 
-func (p *SavedObjectsProvider) DeleteObject(ctx context.Context, obj *SavedObjectOSD) diag.Diagnostics {
+// DeleteObject deletes the object. An object which does not exist anymore is considered deleted.
+func (p *SavedObjectsProvider) DeleteObject(ctx context.Context, obj *SavedObjectOSD) error {
 	req, err := http.NewRequestWithContext(
-		ctx,
+		httplog.WithAttempts(ctx),
 		http.MethodDelete,
-		p.URL(fmt.Sprintf("/%s/%s", obj.Type, obj.ID)),
+		p.URL(fmt.Sprintf("/%s/%s", obj.Type, p.IDNamespace.Apply(obj.ID))),
 		nil,
 	)
 	if err != nil {
-		return diag.FromErr(err)
+		return fmt.Errorf("could not create request for %v: %w", http.MethodDelete, err)
 	}
 
 	req.Header.Set("osd-xsrf", "true")
 	req.Header.Set("Content-Type", "application/json")
+	setTenantHeader(req, obj.Tenant)
 
 	res, err := p.httpClient.Do(req)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("DELETE '%s' failed, err %s, statuscode %d", req.URL.String(), err, res.StatusCode))
+		return fmt.Errorf("DELETE '%s' failed: %w", req.URL.String(), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil
 	}
 	if res.StatusCode != http.StatusOK {
-		response, bodyReadErr := io.ReadAll(res.Body)
-		if bodyReadErr != nil {
-			return diag.FromErr(fmt.Errorf("DELETE '%s' failed with status %d", req.URL.String(), err))
-		}
-		return diag.FromErr(fmt.Errorf("DELETE '%s' failed with status %d\nresponse_body: %s", req.URL.String(), res.StatusCode, string(response)))
+		return apierror.FromResponse(res)
 	}
 
 	return nil
 }
 
+// setTenantHeader selects the tenant of the security plugin. Without the header the tenant stored in the
+// session of the user is used.
+func setTenantHeader(req *http.Request, tenant string) {
+	if tenant != "" {
+		req.Header.Set("securitytenant", tenant)
+	}
+}
+
 func (provider *SavedObjectsProvider) URL(path string) string {
 	base := fmt.Sprintf("%s/api/saved_objects%s", provider.BaseUrl, path)
 	return base
@@ -19,9 +19,12 @@ limitations under the License.
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
 )
 
 func TestIgnoreFieldsOnIndexPatternProperty(t *testing.T) {
@@ -97,9 +100,9 @@ func TestIgnoreFieldsOnIndexPatternProperty(t *testing.T) {
 			defer srv.Close()
 
 			provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, tC.syncFields)
-			obj, diag := provider.GetObject(context.TODO(), tC.obj)
-			if diag != nil {
-				t.Error(diag)
+			obj, err := provider.GetObject(context.TODO(), tC.obj)
+			if err != nil {
+				t.Error(err)
 			}
 
 			attr := map[string]any{}
@@ -142,10 +145,12 @@ func TestNewSavedObjectsProvider(t *testing.T) {
 
 func TestGetObject(t *testing.T) {
 	testCases := []struct {
-		desc        string
-		wantErr     bool
-		obj         *SavedObjectOSD
-		handlerFunc http.HandlerFunc
+		desc           string
+		wantErr        bool
+		wantNotFound   bool
+		transportError bool
+		obj            *SavedObjectOSD
+		handlerFunc    http.HandlerFunc
 	}{
 		{
 			desc:    "must get object",
@@ -161,9 +166,10 @@ func TestGetObject(t *testing.T) {
 			},
 		},
 		{
-			desc:    "must not fail if object does not exist",
-			wantErr: false,
-			obj:     &SavedObjectOSD{Type: "search", ID: "mock-search", SavedObjectPostPayload: SavedObjectPostPayload{Attributes: map[string]any{}, References: []Reference{}}},
+			desc:         "must return ErrNotFound if object does not exist",
+			wantErr:      true,
+			wantNotFound: true,
+			obj:          &SavedObjectOSD{Type: "search", ID: "mock-search", SavedObjectPostPayload: SavedObjectPostPayload{Attributes: map[string]any{}, References: []Reference{}}},
 			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
 				w.WriteHeader(http.StatusNotFound)
 			},
@@ -176,22 +182,42 @@ func TestGetObject(t *testing.T) {
 				w.WriteHeader(http.StatusBadRequest)
 			},
 		},
+		{
+			desc:           "must fail on transport errors",
+			wantErr:        true,
+			transportError: true,
+			obj:            &SavedObjectOSD{Type: "search", ID: "mock-search", SavedObjectPostPayload: SavedObjectPostPayload{Attributes: map[string]any{}, References: []Reference{}}},
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			handler := http.NewServeMux()
-			handler.HandleFunc("/_dashboards/api/saved_objects/search/mock-search", tC.handlerFunc)
+			if tC.handlerFunc != nil {
+				handler.HandleFunc("/_dashboards/api/saved_objects/search/mock-search", tC.handlerFunc)
+			}
 
 			srv := httptest.NewServer(handler)
 			defer srv.Close()
+			if tC.transportError {
+				srv.Close()
+			}
 
 			provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
-			_, diag := provider.GetObject(context.TODO(), tC.obj)
-			if tC.wantErr && diag != nil {
+			obj, err := provider.GetObject(context.TODO(), tC.obj)
+			if errors.Is(err, apierror.ErrNotFound) != tC.wantNotFound {
+				t.Errorf("expected not found: %v but got %v", tC.wantNotFound, err)
+			}
+			if tC.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
 				return
 			}
-			if diag != nil {
-				t.Error(diag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if obj == nil {
+				t.Error("expected object but got nil")
 			}
 		})
 	}
@@ -199,10 +225,12 @@ func TestGetObject(t *testing.T) {
 
 func TestSaveObject(t *testing.T) {
 	testCases := []struct {
-		desc        string
-		wantErr     bool
-		obj         *SavedObjectOSD
-		handlerFunc http.HandlerFunc
+		desc           string
+		wantErr        bool
+		wantConflict   bool
+		transportError bool
+		obj            *SavedObjectOSD
+		handlerFunc    http.HandlerFunc
 	}{
 		{
 			desc:    "must save object",
@@ -212,34 +240,81 @@ func TestSaveObject(t *testing.T) {
 				w.WriteHeader(http.StatusOK)
 			},
 		},
+		{
+			desc:    "must overwrite object which was deleted outside of terraform",
+			wantErr: false,
+			obj:     &SavedObjectOSD{Type: "search", ID: "mock-search", SavedObjectPostPayload: SavedObjectPostPayload{Attributes: map[string]any{}, References: []Reference{}}},
+			handlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("overwrite") != "true" {
+					w.WriteHeader(http.StatusConflict)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			},
+		},
+		{
+			desc:    "must fail when HTTP status not 200 OK",
+			wantErr: true,
+			obj:     &SavedObjectOSD{Type: "search", ID: "mock-search", SavedObjectPostPayload: SavedObjectPostPayload{Attributes: map[string]any{}, References: []Reference{}}},
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+		},
+		{
+			desc:         "must return ConflictError on status 409",
+			wantErr:      true,
+			wantConflict: true,
+			obj:          &SavedObjectOSD{Type: "search", ID: "mock-search", SavedObjectPostPayload: SavedObjectPostPayload{Attributes: map[string]any{}, References: []Reference{}}},
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusConflict)
+			},
+		},
+		{
+			desc:           "must fail on transport errors",
+			wantErr:        true,
+			transportError: true,
+			obj:            &SavedObjectOSD{Type: "search", ID: "mock-search", SavedObjectPostPayload: SavedObjectPostPayload{Attributes: map[string]any{}, References: []Reference{}}},
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			handler := http.NewServeMux()
-			// we define two handle funcs here, first handler saves  the object,  second one will update the object
-			handler.HandleFunc("/_dashboards/api/saved_objects/search/mock-search", tC.handlerFunc)
-			handler.HandleFunc("/_dashboards/api/saved_objects/dashboard/mock-search", tC.handlerFunc)
+			if tC.handlerFunc != nil {
+				// we define two handle funcs here, first handler saves  the object,  second one will update the object
+				handler.HandleFunc("/_dashboards/api/saved_objects/search/mock-search", tC.handlerFunc)
+				handler.HandleFunc("/_dashboards/api/saved_objects/dashboard/mock-search", tC.handlerFunc)
+			}
 
 			srv := httptest.NewServer(handler)
 			defer srv.Close()
+			if tC.transportError {
+				srv.Close()
+			}
 
 			provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
-			diag := provider.SaveObject(context.TODO(), tC.obj)
-			if tC.wantErr && diag != nil {
+			err := provider.SaveObject(context.TODO(), tC.obj)
+			var conflictErr *apierror.ConflictError
+			if errors.As(err, &conflictErr) != tC.wantConflict {
+				t.Errorf("expected conflict: %v but got %v", tC.wantConflict, err)
+			}
+			if tC.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
 				return
 			}
-			if diag != nil {
-				t.Error(diag)
+			if err != nil {
+				t.Error(err)
 			}
 
 			// save the same object twice to update
 			tC.obj.Type = "dashboard"
-			diag = provider.SaveObject(context.TODO(), tC.obj)
-			if tC.wantErr && diag != nil {
+			err = provider.SaveObject(context.TODO(), tC.obj)
+			if tC.wantErr && err != nil {
 				return
 			}
-			if diag != nil {
-				t.Error(diag)
+			if err != nil {
+				t.Error(err)
 			}
 		})
 	}
@@ -247,10 +322,11 @@ func TestSaveObject(t *testing.T) {
 
 func TestDeleteObject(t *testing.T) {
 	testCases := []struct {
-		desc        string
-		wantErr     bool
-		obj         *SavedObjectOSD
-		handlerFunc http.HandlerFunc
+		desc           string
+		wantErr        bool
+		transportError bool
+		obj            *SavedObjectOSD
+		handlerFunc    http.HandlerFunc
 	}{
 		{
 			desc:    "must delete object",
@@ -268,22 +344,44 @@ func TestDeleteObject(t *testing.T) {
 				w.WriteHeader(http.StatusBadRequest)
 			},
 		},
+		{
+			desc:    "must not fail if object was already deleted",
+			wantErr: false,
+			obj:     &SavedObjectOSD{Type: "search", ID: "mock-search", SavedObjectPostPayload: SavedObjectPostPayload{Attributes: map[string]any{}, References: []Reference{}}},
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+		},
+		{
+			desc:           "must fail on transport errors",
+			wantErr:        true,
+			transportError: true,
+			obj:            &SavedObjectOSD{Type: "search", ID: "mock-search", SavedObjectPostPayload: SavedObjectPostPayload{Attributes: map[string]any{}, References: []Reference{}}},
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			handler := http.NewServeMux()
-			handler.HandleFunc("/_dashboards/api/saved_objects/search/mock-search", tC.handlerFunc)
+			if tC.handlerFunc != nil {
+				handler.HandleFunc("/_dashboards/api/saved_objects/search/mock-search", tC.handlerFunc)
+			}
 
 			srv := httptest.NewServer(handler)
 			defer srv.Close()
+			if tC.transportError {
+				srv.Close()
+			}
 
 			provider := NewSavedObjectsProvider(srv.URL+"/_dashboards", http.DefaultClient, false)
-			diag := provider.DeleteObject(context.TODO(), tC.obj)
-			if tC.wantErr && diag != nil {
+			err := provider.DeleteObject(context.TODO(), tC.obj)
+			if tC.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
 				return
 			}
-			if diag != nil {
-				t.Error(diag)
+			if err != nil {
+				t.Error(err)
 			}
 		})
 	}
@@ -16,9 +16,16 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 type SavedObjectOSD struct {
 	Type string `json:"type,omitempty"`
 	ID   string `json:"id,omitempty"`
+	// Tenant of the security plugin the object belongs to, the tenant of the user if empty
+	Tenant string `json:"-"`
 	SavedObjectPostPayload
 }
 
@@ -39,4 +46,21 @@ type Reference struct {
 	ID   string `json:"id"`
 	Name string `json:"name,omitempty"`
 	Type string `json:"type"`
+	// External references point to an object outside of the IDNamespace, so their ID is sent as given
+	External bool `json:"-"`
+}
+
+// ToTF returns the object with stringified attributes, as returned by GetObject.
+func (o *SavedObjectOSD) ToTF() (*SavedObjectTF, error) {
+	attributes, err := json.Marshal(o.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot stringify attributes of %s %s: %w", o.Type, o.ID, err)
+	}
+
+	return &SavedObjectTF{
+		Type:       o.Type,
+		ID:         o.ID,
+		Attributes: string(attributes),
+		References: o.References,
+	}, nil
 }
@@ -0,0 +1,125 @@
+package sigv4
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// Credentials are the AWS credentials used to sign requests.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// CanExpire is set if the credentials are only valid until Expires
+	CanExpire bool
+	Expires   time.Time
+}
+
+// CredentialsProvider retrieves fresh credentials on every call, caching is done by the Transport.
+type CredentialsProvider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsProviderFunc is an adapter to allow the use of ordinary functions as CredentialsProvider.
+type CredentialsProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Retrieve implements the CredentialsProvider interface.
+func (f CredentialsProviderFunc) Retrieve(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// AWSCredentials adapts the credentials of an AWS session, e.g. from the default credential chain.
+func AWSCredentials(creds *credentials.Credentials) CredentialsProvider {
+	return CredentialsProviderFunc(func(ctx context.Context) (Credentials, error) {
+		// the cache of the AWS credentials is bypassed, the Transport only asks for credentials when it needs new ones
+		creds.Expire()
+		value, err := creds.GetWithContext(ctx)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("could not retrieve AWS credentials: %w", err)
+		}
+
+		result := Credentials{
+			AccessKeyID:     value.AccessKeyID,
+			SecretAccessKey: value.SecretAccessKey,
+			SessionToken:    value.SessionToken,
+		}
+		if expires, err := creds.ExpiresAt(); err == nil && !expires.IsZero() {
+			result.CanExpire = true
+			result.Expires = expires
+		}
+
+		return result, nil
+	})
+}
+
+// credentialsCache caches the credentials until they expire. Within refreshWindow before they expire, they are
+// refreshed in the background, so requests do not wait for new credentials. Providers may return credentials
+// which are already within the refresh window, so at most one refresh is running and refreshes are at least
+// minRefreshInterval apart.
+type credentialsCache struct {
+	provider           CredentialsProvider
+	refreshWindow      time.Duration
+	minRefreshInterval time.Duration
+	now                func() time.Time
+
+	mu          sync.Mutex
+	creds       *Credentials
+	refreshing  bool
+	lastRefresh time.Time
+}
+
+func (c *credentialsCache) get(ctx context.Context) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.creds == nil || (c.creds.CanExpire && !now.Before(c.creds.Expires)) {
+		creds, err := c.provider.Retrieve(ctx)
+		if err != nil {
+			return Credentials{}, err
+		}
+		c.creds = &creds
+		c.lastRefresh = now
+	}
+
+	if c.creds.CanExpire && !now.Before(c.creds.Expires.Add(-c.refreshWindow)) && !c.refreshing &&
+		now.Sub(c.lastRefresh) >= c.minRefreshInterval {
+		c.refreshing = true
+		c.lastRefresh = now
+		go c.refresh()
+	}
+
+	return *c.creds, nil
+}
+
+func (c *credentialsCache) refresh() {
+	creds, err := c.provider.Retrieve(context.Background())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshing = false
+	// on errors the cached credentials are used until they expire, a later request tries again
+	if err == nil {
+		c.creds = &creds
+	}
+}
@@ -0,0 +1,54 @@
+package sigv4
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/rest"
+)
+
+// sign adds the X-Amz-Date, X-Amz-Security-Token and Authorization headers to req. body is the content of the
+// body of req, nil if it has none. The body of req itself is not read.
+func sign(req *http.Request, body []byte, creds Credentials, service, region string, t time.Time) error {
+	// commas escaped in the path are escaped once more, like OpenSearch Service does when it checks the signature
+	if strings.Contains(req.URL.RawPath, "%2C") {
+		req.URL.RawPath = rest.EscapePath(req.URL.RawPath, false)
+	}
+
+	signer := v4.NewSigner(credentials.NewStaticCredentials(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken))
+	// the signer replaces the body with the one it hashed
+	reqBody := req.Body
+	var err error
+	if body == nil {
+		_, err = signer.Sign(req, nil, service, region, t)
+	} else {
+		_, err = signer.Sign(req, bytes.NewReader(body), service, region, t)
+	}
+	req.Body = reqBody
+	if err != nil {
+		return fmt.Errorf("could not sign request: %w", err)
+	}
+
+	return nil
+}
@@ -21,24 +21,33 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
-	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
-	"github.com/aws/aws-sdk-go/private/protocol/rest"
 )
 
+const (
+	// defaultRefreshWindow is how long before they expire credentials are refreshed in the background.
+	defaultRefreshWindow = 5 * time.Minute
+	// minRefreshInterval is the minimum time between two refreshes of credentials.
+	minRefreshInterval = 30 * time.Second
+)
+
+// SigV4Transport signs requests with AWS Signature Version 4 before passing them to the next round-tripper.
+// It is safe for concurrent use and meant to be long-lived, so the credentials are cached.
 type SigV4Transport struct {
-	signer *v4.Signer
-	config *Config
-	next   http.RoundTripper
+	config      Config
+	credentials *credentialsCache
+	next        http.RoundTripper
 }
 
 type Config struct {
 	Service string
 	Region  string
+	// RefreshWindow is how long before they expire credentials are refreshed, 5 minutes if not set
+	RefreshWindow time.Duration
+	// Now returns the signing time, time.Now if not set
+	Now func() time.Time
 }
 
 // The RoundTripperFunc type is an adapter to allow the use of ordinary
@@ -51,30 +60,45 @@ func (rt RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
 	return rt(r)
 }
 
-// NewSigner instantiates a new signing middleware with an optional succeeding
+// NewSigner instantiates a new signing middleware for the AWS credentials with an optional succeeding
 // middleware. The http.DefaultTransport will be used if nil.
-func NewSigner(cfg *Config, creds *credentials.Credentials, next http.RoundTripper) (http.RoundTripper, error) {
-	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
-		if next == nil {
-			next = http.DefaultTransport
-		}
+func NewSigner(cfg *Config, creds *credentials.Credentials, next http.RoundTripper) (*SigV4Transport, error) {
+	return NewTransport(cfg, AWSCredentials(creds), next), nil
+}
 
-		signer, err := createSigner(creds, false)
-		if err != nil {
-			return nil, err
-		}
-		m := &SigV4Transport{
-			config: cfg,
-			next:   next,
-			signer: signer,
-		}
-		return m.exec(r)
-	}), nil
+// NewTransport instantiates a new signing middleware for the credentials provider with an optional succeeding
+// middleware. The http.DefaultTransport will be used if nil.
+func NewTransport(cfg *Config, provider CredentialsProvider, next http.RoundTripper) *SigV4Transport {
+	config := *cfg
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	if config.RefreshWindow == 0 {
+		config.RefreshWindow = defaultRefreshWindow
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &SigV4Transport{
+		config: config,
+		credentials: &credentialsCache{
+			provider:           provider,
+			refreshWindow:      config.RefreshWindow,
+			minRefreshInterval: minRefreshInterval,
+			now:                config.Now,
+		},
+		next: next,
+	}
 }
 
-func (m *SigV4Transport) exec(origReq *http.Request) (*http.Response, error) {
+// RoundTrip signs a clone of the request and passes it on. The original request is not modified.
+func (m *SigV4Transport) RoundTrip(origReq *http.Request) (*http.Response, error) {
 	req, err := m.createSignedRequest(origReq)
 	if err != nil {
+		if origReq.Body != nil {
+			origReq.Body.Close()
+		}
 		return nil, err
 	}
 
@@ -83,50 +107,55 @@ func (m *SigV4Transport) exec(origReq *http.Request) (*http.Response, error) {
 }
 
 func (m *SigV4Transport) createSignedRequest(origReq *http.Request) (*http.Request, error) {
-	req, err := http.NewRequest(origReq.Method, origReq.URL.String(), origReq.Body)
+	creds, err := m.credentials.get(origReq.Context())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP req: %w", err)
-	}
-
-	body := bytes.NewReader([]byte{})
-	if req.Body != nil {
-		b, err := io.ReadAll(req.Body)
-		if err != nil {
-			return nil, fmt.Errorf("could not read body to sign: %w", err)
-		}
-		body = bytes.NewReader(b)
-	}
-
-	if strings.Contains(req.URL.RawPath, "%2C") {
-		req.URL.RawPath = rest.EscapePath(req.URL.RawPath, false)
+		return nil, err
 	}
 
-	_, err = m.signer.Sign(req, body, m.config.Service, m.config.Region, time.Now().UTC())
+	req := origReq.Clone(origReq.Context())
+	body, err := readBody(req)
 	if err != nil {
-		return nil, fmt.Errorf("signing body failed: %w", err)
+		return nil, err
 	}
 
-	copyHeaderWithoutOverwrite(req.Header, origReq.Header)
+	if err := sign(req, body, creds, m.config.Service, m.config.Region, m.config.Now()); err != nil {
+		return nil, err
+	}
 
 	return req, nil
 }
 
-func createSigner(c *credentials.Credentials, verboseMode bool) (*v4.Signer, error) {
-	signerOpts := func(s *v4.Signer) {
-		if verboseMode {
-			s.Debug = aws.LogDebugWithSigning
-		}
+// readBody returns the content of the body of req, nil if it has none. If the body can be read again with GetBody,
+// a fresh copy is read and the body itself is sent untouched. Otherwise it is buffered once and GetBody is set,
+// so the signed request can be retried.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
 	}
 
-	return v4.NewSigner(c, signerOpts), nil
-}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("could not get body to sign: %w", err)
+		}
+		defer body.Close()
 
-func copyHeaderWithoutOverwrite(dst, src http.Header) {
-	for k, vv := range src {
-		if _, ok := dst[k]; !ok {
-			for _, v := range vv {
-				dst.Add(k, v)
-			}
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("could not read body to sign: %w", err)
 		}
+		return b, nil
 	}
+
+	b, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read body to sign: %w", err)
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+
+	return b, nil
 }
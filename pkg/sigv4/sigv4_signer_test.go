@@ -0,0 +1,411 @@
+package sigv4
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// the credentials, time and scope of the AWS SigV4 test suite
+var (
+	testSuiteCredentials = Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	testSuiteTime        = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	testSuiteConfig      = &Config{Service: "service", Region: "us-east-1", Now: func() time.Time { return testSuiteTime }}
+)
+
+const testSuiteCredential = "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "
+
+func staticProvider(creds Credentials) CredentialsProvider {
+	return CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		return creds, nil
+	})
+}
+
+// capture returns a round-tripper which records the last request instead of sending it.
+func capture(last **http.Request) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		*last = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+}
+
+func TestTestSuiteVectors(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		method  string
+		url     string
+		body    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			desc:   "get-vanilla",
+			method: http.MethodGet,
+			url:    "https://example.amazonaws.com/",
+			want:   "SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			desc:   "get-vanilla-query-order-key-case",
+			method: http.MethodGet,
+			url:    "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			want:   "SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			desc:   "get-vanilla-empty-query-key",
+			method: http.MethodGet,
+			url:    "https://example.amazonaws.com/?Param1=value1",
+			want:   "SignedHeaders=host;x-amz-date, Signature=a67d582fa61cc504c4bae71f336f98b97f1ea3c7a6bfe1b6e45aec72011b9aeb",
+		},
+		{
+			desc:   "get-vanilla-utf8-query",
+			method: http.MethodGet,
+			url:    "https://example.amazonaws.com/?ሴ=bar",
+			want:   "SignedHeaders=host;x-amz-date, Signature=2cdec8eed098649ff3a119c94853b13c643bcf08f8b0a1d91e12c9027818dd04",
+		},
+		{
+			desc:   "get-unreserved",
+			method: http.MethodGet,
+			url:    "https://example.amazonaws.com/-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+			want:   "SignedHeaders=host;x-amz-date, Signature=07ef7494c76fa4850883e2b006601f940f8a34d404d0cfa977f52a65bbf5f24f",
+		},
+		{
+			desc:    "get-header-value-trim",
+			method:  http.MethodGet,
+			url:     "https://example.amazonaws.com/",
+			headers: map[string]string{"My-Header1": " value1", "My-Header2": ` "a   b   c"`},
+			want:    "SignedHeaders=host;my-header1;my-header2;x-amz-date, Signature=acc3ed3afb60bb290fc8d2dd0098b9911fcaa05412b367055dee359757a9c736",
+		},
+		{
+			desc:   "post-vanilla",
+			method: http.MethodPost,
+			url:    "https://example.amazonaws.com/",
+			want:   "SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			desc:    "post-x-www-form-urlencoded",
+			method:  http.MethodPost,
+			url:     "https://example.amazonaws.com/",
+			body:    "Param1=value1",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			want:    "SignedHeaders=content-type;host;x-amz-date, Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var signed *http.Request
+			transport := NewTransport(testSuiteConfig, staticProvider(testSuiteCredentials), capture(&signed))
+
+			var body io.Reader
+			if tC.body != "" {
+				body = strings.NewReader(tC.body)
+			}
+			req, err := http.NewRequest(tC.method, tC.url, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range tC.headers {
+				req.Header.Set(key, value)
+			}
+
+			if _, err := transport.RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := signed.Header.Get("Authorization"); got != testSuiteCredential+tC.want {
+				t.Errorf("expected %s\nbut got  %s", testSuiteCredential+tC.want, got)
+			}
+			if got := signed.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("expected X-Amz-Date 20150830T123600Z but got %s", got)
+			}
+			if req.Header.Get("Authorization") != "" {
+				t.Error("expected the original request not to be modified")
+			}
+		})
+	}
+}
+
+func TestSessionToken(t *testing.T) {
+	var signed *http.Request
+	creds := testSuiteCredentials
+	creds.SessionToken = "session-token"
+	transport := NewTransport(testSuiteConfig, staticProvider(creds), capture(&signed))
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := signed.Header.Get("X-Amz-Security-Token"); got != "session-token" {
+		t.Errorf("expected session token header but got %q", got)
+	}
+	if got := signed.Header.Get("Authorization"); !strings.Contains(got, "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+		t.Errorf("expected session token to be signed but got %s", got)
+	}
+}
+
+func TestCommas(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		url     string
+		escaped string
+	}{
+		{
+			desc:    "must sign escaped commas in the path like literal ones",
+			url:     "https://example.amazonaws.com/api/saved_objects/dashboard/a,b",
+			escaped: "https://example.amazonaws.com/api/saved_objects/dashboard/a%2Cb",
+		},
+		{
+			desc:    "must sign escaped commas in the query like literal ones",
+			url:     "https://example.amazonaws.com/api/saved_objects/_find?fields=a,b",
+			escaped: "https://example.amazonaws.com/api/saved_objects/_find?fields=a%2Cb",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var signed *http.Request
+			transport := NewTransport(testSuiteConfig, staticProvider(testSuiteCredentials), capture(&signed))
+
+			var signatures []string
+			for _, url := range []string{tC.url, tC.escaped} {
+				req, _ := http.NewRequest(http.MethodGet, url, nil)
+				if _, err := transport.RoundTrip(req); err != nil {
+					t.Fatal(err)
+				}
+				if signed.URL.Path != req.URL.Path || signed.URL.Query().Encode() != req.URL.Query().Encode() {
+					t.Errorf("expected %s to be sent but got %s", req.URL, signed.URL)
+				}
+				signatures = append(signatures, signed.Header.Get("Authorization"))
+			}
+
+			if signatures[0] != signatures[1] {
+				t.Errorf("expected the same signature but got\n%s\n%s", signatures[0], signatures[1])
+			}
+		})
+	}
+}
+
+func TestBodyCanBeRetried(t *testing.T) {
+	testCases := []struct {
+		desc string
+		body func() io.Reader
+	}{
+		{
+			desc: "must hash a copy of bodies with GetBody",
+			body: func() io.Reader { return bytes.NewBufferString(`{"changes":{}}`) },
+		},
+		{
+			desc: "must buffer bodies without GetBody once",
+			body: func() io.Reader { return io.NopCloser(strings.NewReader(`{"changes":{}}`)) },
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var signed *http.Request
+			transport := NewTransport(testSuiteConfig, staticProvider(testSuiteCredentials), capture(&signed))
+
+			req, _ := http.NewRequest(http.MethodPost, "https://example.amazonaws.com/", tC.body())
+			if _, err := transport.RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+
+			sent, _ := io.ReadAll(signed.Body)
+			if string(sent) != `{"changes":{}}` {
+				t.Errorf("expected body to be sent but got %q", sent)
+			}
+			if signed.GetBody == nil {
+				t.Fatal("expected GetBody to be set")
+			}
+			retry, _ := signed.GetBody()
+			again, _ := io.ReadAll(retry)
+			if string(again) != `{"changes":{}}` {
+				t.Errorf("expected body to be readable again but got %q", again)
+			}
+		})
+	}
+}
+
+func TestCredentialsCache(t *testing.T) {
+	now := testSuiteTime
+	var mu sync.Mutex
+	retrieved := make(chan int, 10)
+	calls := 0
+	provider := CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		creds := testSuiteCredentials
+		creds.SessionToken = string(rune('a' + calls - 1))
+		creds.CanExpire = true
+		creds.Expires = now.Add(time.Hour)
+		retrieved <- calls
+		return creds, nil
+	})
+
+	cache := &credentialsCache{provider: provider, refreshWindow: 5 * time.Minute, now: func() time.Time { return now }}
+	get := func() string {
+		t.Helper()
+		creds, err := cache.get(context.TODO())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return creds.SessionToken
+	}
+
+	if token := get(); token != "a" {
+		t.Errorf("expected first credentials but got %q", token)
+	}
+	<-retrieved
+	if token := get(); token != "a" {
+		t.Errorf("expected cached credentials but got %q", token)
+	}
+
+	// within the refresh window the cached credentials are used while new ones are retrieved in the background
+	now = testSuiteTime.Add(56 * time.Minute)
+	if token := get(); token != "a" {
+		t.Errorf("expected cached credentials during refresh but got %q", token)
+	}
+	<-retrieved
+	waitFor(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return !cache.refreshing
+	})
+	if token := get(); token != "b" {
+		t.Errorf("expected refreshed credentials but got %q", token)
+	}
+
+	// expired credentials are retrieved before signing
+	now = testSuiteTime.Add(3 * time.Hour)
+	if token := get(); token != "c" {
+		t.Errorf("expected new credentials after expiry but got %q", token)
+	}
+}
+
+func TestCredentialsRefreshRateLimit(t *testing.T) {
+	now := testSuiteTime
+	var calls atomic.Int32
+	release := make(chan struct{})
+	provider := CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		if calls.Add(1) > 1 {
+			<-release
+		}
+		creds := testSuiteCredentials
+		// the credentials are returned within the refresh window already
+		creds.CanExpire = true
+		creds.Expires = testSuiteTime.Add(2 * time.Minute)
+		return creds, nil
+	})
+
+	cache := &credentialsCache{provider: provider, refreshWindow: 5 * time.Minute, minRefreshInterval: 30 * time.Second, now: func() time.Time { return now }}
+	get := func() {
+		t.Helper()
+		if _, err := cache.get(context.TODO()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	refreshing := func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return cache.refreshing
+	}
+
+	for range 10 {
+		get()
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected no refresh right after retrieving the credentials but got %d calls", got)
+	}
+
+	// one refresh is started after the minimum interval and no further one while it is running
+	now = now.Add(31 * time.Second)
+	get()
+	now = now.Add(31 * time.Second)
+	get()
+	if !refreshing() {
+		t.Fatal("expected a refresh to be running")
+	}
+	close(release)
+	waitFor(t, func() bool { return !refreshing() })
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected one refresh but got %d calls", got)
+	}
+}
+
+func TestCredentialsError(t *testing.T) {
+	provider := CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		return Credentials{}, errors.New("no credentials")
+	})
+	var signed *http.Request
+	transport := NewTransport(testSuiteConfig, provider, capture(&signed))
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Error("expected error but got none")
+	}
+	if signed != nil {
+		t.Error("expected request not to be sent")
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	for range 100 {
+		if condition() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
+
+func BenchmarkRoundTrip(b *testing.B) {
+	sizes := []struct {
+		name string
+		size int
+	}{
+		{name: "empty", size: 0},
+		{name: "1KiB", size: 1 << 10},
+		{name: "1MiB", size: 1 << 20},
+	}
+	for _, s := range sizes {
+		b.Run(s.name, func(b *testing.B) {
+			var signed *http.Request
+			transport := NewTransport(testSuiteConfig, staticProvider(testSuiteCredentials), capture(&signed))
+			body := bytes.Repeat([]byte("a"), s.size)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(s.size))
+			for b.Loop() {
+				req, _ := http.NewRequest(http.MethodPost, "https://example.amazonaws.com/_dashboards/api/saved_objects/_bulk_get", bytes.NewReader(body))
+				req.Header.Set("osd-xsrf", "true")
+				req.Header.Set("Content-Type", "application/json")
+				if _, err := transport.RoundTrip(req); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
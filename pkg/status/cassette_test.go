@@ -0,0 +1,45 @@
+package status
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/cassette"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/cassettetest"
+)
+
+func TestCassetteStatus(t *testing.T) {
+	for _, version := range cassette.Versions {
+		t.Run(version, func(t *testing.T) {
+			baseURL, client := cassettetest.Start(t, version, "status", cassette.Options{})
+			p := NewProvider(baseURL, client)
+
+			status, err := p.GetStatus(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			major, _, _ := strings.Cut(version, ".")
+			if strconv.Itoa(status.Version.Major()) != major || status.State() != "green" {
+				t.Errorf("expected a green OpenSearch Dashboards %s but got %+v", version, status)
+			}
+		})
+	}
+}
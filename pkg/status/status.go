@@ -0,0 +1,118 @@
+// Package status contains the client of the status API of OpenSearch Dashboards, which is used to check the
+// connection and to detect the version of OpenSearch Dashboards.
+package status
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/httplog"
+)
+
+// Status is the status of OpenSearch Dashboards.
+type Status struct {
+	Version Version `json:"version"`
+	Status  struct {
+		Overall struct {
+			// State is green, yellow or red
+			State string `json:"state"`
+		} `json:"overall"`
+	} `json:"status"`
+}
+
+// State returns the overall state, i.e. green, yellow or red.
+func (s *Status) State() string {
+	return s.Status.Overall.State
+}
+
+// Version is the version of OpenSearch Dashboards, e.g. 2.11.0.
+type Version struct {
+	Number      string `json:"number"`
+	BuildNumber int    `json:"build_number"`
+}
+
+func (v Version) String() string {
+	return v.Number
+}
+
+// Major returns the major version, e.g. 2 for 2.11.0, 0 if the version number cannot be parsed.
+func (v Version) Major() int {
+	major, _, _ := strings.Cut(v.Number, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
+
+// Client is the interface of the status API implemented by Provider.
+type Client interface {
+	GetStatus(ctx context.Context) (*Status, error)
+}
+
+var _ Client = &Provider{}
+
+type Provider struct {
+	Url        string
+	httpClient *http.Client
+}
+
+func NewProvider(baseUrl string, client *http.Client) *Provider {
+	return &Provider{
+		Url:        fmt.Sprintf("%s/api/status", baseUrl),
+		httpClient: client,
+	}
+}
+
+// GetStatus returns the status of OpenSearch Dashboards. An error is returned if the response is not the one of
+// OpenSearch Dashboards, e.g. the HTML login page of a proxy.
+func (p *Provider) GetStatus(ctx context.Context) (*Status, error) {
+	req, err := http.NewRequestWithContext(httplog.WithAttempts(ctx), http.MethodGet, p.Url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not build request to GET %v %w", p.Url, err)
+	}
+	req.Header.Set("osd-xsrf", "true")
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET '%v' failed: %w", req.URL.String(), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, apierror.FromResponse(res)
+	}
+
+	result := &Status{}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("GET '%v' did not return the status of OpenSearch Dashboards, cannot decode response body: %w", req.URL.String(), err)
+	}
+	if result.Version.Number == "" {
+		return nil, fmt.Errorf("GET '%v' did not return the status of OpenSearch Dashboards, the response has no version", req.URL.String())
+	}
+
+	return result, nil
+}
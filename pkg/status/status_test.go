@@ -0,0 +1,126 @@
+package status
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/apierror"
+)
+
+func TestGetStatus(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		wantErr     bool
+		wantStatus  int
+		wantVersion string
+		wantMajor   int
+		wantState   string
+		handlerFunc http.HandlerFunc
+	}{
+		{
+			desc:        "must return the version and state",
+			wantVersion: "2.11.0",
+			wantMajor:   2,
+			wantState:   "green",
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(`{"name":"opensearch-dashboards","version":{"number":"2.11.0","build_number":7052},"status":{"overall":{"state":"green"}}}`))
+			},
+		},
+		{
+			desc:        "must return degraded states",
+			wantVersion: "1.3.6",
+			wantMajor:   1,
+			wantState:   "yellow",
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(`{"version":{"number":"1.3.6","build_number":4970},"status":{"overall":{"state":"yellow"}}}`))
+			},
+		},
+		{
+			desc:       "must return an API error when not authenticated",
+			wantErr:    true,
+			wantStatus: http.StatusForbidden,
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+		{
+			desc:    "must fail when the response is not JSON",
+			wantErr: true,
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(`<html><body>Login</body></html>`))
+			},
+		},
+		{
+			desc:    "must fail when the response has no version",
+			wantErr: true,
+			handlerFunc: func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(`{"ok":true}`))
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			handler := http.NewServeMux()
+			handler.HandleFunc("/_dashboards/api/status", tC.handlerFunc)
+
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			provider := NewProvider(srv.URL+"/_dashboards", http.DefaultClient)
+			status, err := provider.GetStatus(context.TODO())
+			if tC.wantErr {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				var apiErr *apierror.APIError
+				if isAPIErr := errors.As(err, &apiErr); isAPIErr != (tC.wantStatus != 0) || (isAPIErr && apiErr.StatusCode != tC.wantStatus) {
+					t.Errorf("expected status %d but got %v", tC.wantStatus, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.Version.String() != tC.wantVersion || status.Version.Major() != tC.wantMajor || status.State() != tC.wantState {
+				t.Errorf("expected version %s (%d) in state %s but got %+v", tC.wantVersion, tC.wantMajor, tC.wantState, status)
+			}
+		})
+	}
+}
+
+func TestMajor(t *testing.T) {
+	testCases := []struct {
+		number string
+		want   int
+	}{
+		{number: "1.3.6", want: 1},
+		{number: "2.11.0", want: 2},
+		{number: "3.0.0-beta1", want: 3},
+		{number: "", want: 0},
+		{number: "latest", want: 0},
+	}
+	for _, tC := range testCases {
+		if got := (Version{Number: tC.number}).Major(); got != tC.want {
+			t.Errorf("expected major version %d of %q but got %d", tC.want, tC.number, got)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package visualization
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"fmt"
+
+	"github.com/hjson/hjson-go/v4"
+)
+
+// ParseHJSON parses HJSON (https://hjson.github.io), which the Vega editor of OpenSearch Dashboards
+// accepts in addition to JSON. Every JSON document is valid HJSON. Objects and numbers are returned as
+// map[string]any and float64, like encoding/json does.
+func ParseHJSON(s string) (any, error) {
+	var v any
+	if err := hjson.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("invalid HJSON: %w", err)
+	}
+
+	return v, nil
+}
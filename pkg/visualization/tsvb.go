@@ -0,0 +1,163 @@
+package visualization
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+)
+
+const tsvbType = "metrics"
+
+// TSVBPanelTypes are the panel types supported by the Time Series Visual Builder.
+var TSVBPanelTypes = []string{"timeseries", "metric", "top_n", "gauge", "markdown", "table"}
+
+// TSVBMetricTypes are the metric aggregations supported by the Time Series Visual Builder.
+var TSVBMetricTypes = []string{
+	"avg", "avg_bucket", "calculation", "cardinality", "count", "cumulative_sum", "derivative", "filter_ratio",
+	"math", "max", "max_bucket", "min", "min_bucket", "moving_average", "percentile", "percentile_rank",
+	"positive_only", "positive_rate", "serial_diff", "series_agg", "static", "std_deviation",
+	"std_deviation_bucket", "sum", "sum_bucket", "sum_of_squares", "sum_of_squares_bucket", "top_hit",
+	"value_count", "variance", "variance_bucket",
+}
+
+// metric aggregations which need a field of the index to aggregate on.
+var tsvbFieldMetricTypes = map[string]bool{
+	"avg": true, "cardinality": true, "max": true, "min": true, "percentile": true, "percentile_rank": true,
+	"positive_rate": true, "std_deviation": true, "sum": true, "sum_of_squares": true, "top_hit": true,
+	"value_count": true, "variance": true,
+}
+
+// Unknown stands in for parameters whose values are not known yet, e.g. while Terraform plans. ValidateTSVBParams
+// skips them.
+var Unknown any = unknownValue{}
+
+type unknownValue struct{}
+
+func isUnknown(v any) bool {
+	_, ok := v.(unknownValue)
+	return ok
+}
+
+// NewTSVBVisualization creates a Time Series Visual Builder visualization from its panel parameters.
+func NewTSVBVisualization(id, title, description string, params map[string]any) *Visualization {
+	return &Visualization{
+		ID:          id,
+		Title:       title,
+		Description: description,
+		Type:        tsvbType,
+		Params:      params,
+	}
+}
+
+// ValidateTSVBParams checks the panel parameters of a Time Series Visual Builder visualization for
+// mistakes that the editor of OpenSearch Dashboards would only report when the visualization is opened.
+func ValidateTSVBParams(params map[string]any) error {
+	var errs []error
+
+	if t, _ := params["type"].(string); !isUnknown(params["type"]) && !contains(TSVBPanelTypes, t) {
+		errs = append(errs, fmt.Errorf("type: %q is not a valid panel type, expected one of %v", t, TSVBPanelTypes))
+	}
+
+	series, ok := params["series"].([]any)
+	if !ok || len(series) == 0 {
+		return errors.Join(append(errs, errors.New("series: at least one series is required"))...)
+	}
+
+	seriesIDs := map[string]bool{}
+	for i, s := range series {
+		path := fmt.Sprintf("series[%d]", i)
+		seriesMap, ok := s.(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: must be an object, got %s", path, jsonTypeName(s)))
+			continue
+		}
+
+		id, _ := seriesMap["id"].(string)
+		switch {
+		case isUnknown(seriesMap["id"]):
+		case id == "":
+			errs = append(errs, fmt.Errorf("%s.id: must be set", path))
+		case seriesIDs[id]:
+			errs = append(errs, fmt.Errorf("%s.id: %q is used by more than one series", path, id))
+		}
+		seriesIDs[id] = true
+
+		if mode, _ := seriesMap["split_mode"].(string); mode == "terms" {
+			if field, _ := seriesMap["terms_field"].(string); field == "" && !isUnknown(seriesMap["terms_field"]) {
+				errs = append(errs, fmt.Errorf("%s.terms_field: must be set if split_mode is terms", path))
+			}
+		}
+
+		if !isUnknown(seriesMap["metrics"]) {
+			errs = append(errs, validateTSVBMetrics(path, seriesMap["metrics"])...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateTSVBMetrics(path string, metrics any) []error {
+	metricList, ok := metrics.([]any)
+	if !ok || len(metricList) == 0 {
+		return []error{fmt.Errorf("%s.metrics: at least one metric is required", path)}
+	}
+
+	var errs []error
+	metricIDs := map[string]bool{}
+	for i, m := range metricList {
+		metricPath := fmt.Sprintf("%s.metrics[%d]", path, i)
+		metric, ok := m.(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: must be an object, got %s", metricPath, jsonTypeName(m)))
+			continue
+		}
+
+		id, _ := metric["id"].(string)
+		switch {
+		case isUnknown(metric["id"]):
+		case id == "":
+			errs = append(errs, fmt.Errorf("%s.id: must be set", metricPath))
+		case metricIDs[id]:
+			errs = append(errs, fmt.Errorf("%s.id: %q is used by more than one metric of the series", metricPath, id))
+		}
+		metricIDs[id] = true
+
+		t, _ := metric["type"].(string)
+		if isUnknown(metric["type"]) {
+			continue
+		}
+		if !contains(TSVBMetricTypes, t) {
+			errs = append(errs, fmt.Errorf("%s.type: %q is not a valid metric type", metricPath, t))
+			continue
+		}
+		if field, _ := metric["field"].(string); tsvbFieldMetricTypes[t] && field == "" && !isUnknown(metric["field"]) {
+			errs = append(errs, fmt.Errorf("%s.field: must be set for metrics of type %s", metricPath, t))
+		}
+	}
+
+	return errs
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
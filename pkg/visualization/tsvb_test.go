@@ -0,0 +1,98 @@
+package visualization
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTSVBParams(t *testing.T) {
+	series := func(s ...map[string]any) []any {
+		result := make([]any, len(s))
+		for i := range s {
+			result[i] = s[i]
+		}
+		return result
+	}
+	count := []any{map[string]any{"id": "m1", "type": "count"}}
+
+	testCases := []struct {
+		desc    string
+		params  map[string]any
+		wantErr string
+	}{
+		{
+			desc:   "must accept valid params",
+			params: map[string]any{"type": "timeseries", "series": series(map[string]any{"id": "s1", "metrics": count})},
+		},
+		{
+			desc:    "must reject unknown panel type",
+			params:  map[string]any{"type": "pie", "series": series(map[string]any{"id": "s1", "metrics": count})},
+			wantErr: "not a valid panel type",
+		},
+		{
+			desc:    "must reject missing series",
+			params:  map[string]any{"type": "timeseries"},
+			wantErr: "at least one series is required",
+		},
+		{
+			desc: "must reject duplicate series ids",
+			params: map[string]any{"type": "timeseries", "series": series(
+				map[string]any{"id": "s1", "metrics": count},
+				map[string]any{"id": "s1", "metrics": count},
+			)},
+			wantErr: `series[1].id: "s1" is used by more than one series`,
+		},
+		{
+			desc:    "must reject terms split without field",
+			params:  map[string]any{"type": "timeseries", "series": series(map[string]any{"id": "s1", "split_mode": "terms", "metrics": count})},
+			wantErr: "series[0].terms_field: must be set",
+		},
+		{
+			desc: "must reject metric without field",
+			params: map[string]any{"type": "metric", "series": series(map[string]any{"id": "s1", "metrics": []any{
+				map[string]any{"id": "m1", "type": "avg"},
+			}})},
+			wantErr: "series[0].metrics[0].field: must be set for metrics of type avg",
+		},
+		{
+			desc: "must skip unknown values",
+			params: map[string]any{"type": Unknown, "series": series(
+				map[string]any{"id": Unknown, "split_mode": "terms", "terms_field": Unknown, "metrics": []any{
+					map[string]any{"id": Unknown, "type": "avg", "field": Unknown},
+					map[string]any{"id": "m2", "type": Unknown},
+				}},
+				map[string]any{"id": "s2", "metrics": Unknown},
+			)},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := ValidateTSVBParams(tC.params)
+			if tC.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error but got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tC.wantErr) {
+				t.Errorf("expected error containing %q but got %v", tC.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package visualization
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	vegaType = "vega"
+
+	vegaSchemaPrefix     = "https://vega.github.io/schema/vega/"
+	vegaLiteSchemaPrefix = "https://vega.github.io/schema/vega-lite/"
+)
+
+// NewVegaVisualization creates a visualization of type vega. The spec is stored as it is, so comments and
+// formatting of HJSON specs are preserved in the Vega editor.
+func NewVegaVisualization(id, title, description, spec string) *Visualization {
+	return &Visualization{
+		ID:          id,
+		Title:       title,
+		Description: description,
+		Type:        vegaType,
+		Params:      map[string]any{"spec": spec},
+	}
+}
+
+// VegaSpec returns the spec of a visualization of type vega.
+func (v *Visualization) VegaSpec() (string, error) {
+	if v.Type != vegaType {
+		return "", fmt.Errorf("visualization %q has type %q, expected %q", v.ID, v.Type, vegaType)
+	}
+	spec, ok := v.Params["spec"].(string)
+	if !ok {
+		return "", fmt.Errorf("visualization %q has no spec", v.ID)
+	}
+
+	return spec, nil
+}
+
+// ValidateVegaSpec parses a Vega or Vega-Lite spec given as JSON or HJSON and checks that $schema
+// references Vega or Vega-Lite and that every data url querying OpenSearch has a valid index and body.
+func ValidateVegaSpec(spec string) error {
+	parsed, err := ParseHJSON(spec)
+	if err != nil {
+		return fmt.Errorf("spec is neither valid JSON nor HJSON: %w", err)
+	}
+
+	root, ok := parsed.(map[string]any)
+	if !ok {
+		return fmt.Errorf("spec must be an object, got %s", jsonTypeName(parsed))
+	}
+
+	var errs []error
+	schema, ok := root["$schema"].(string)
+	switch {
+	case !ok:
+		errs = append(errs, errors.New("$schema: must be set to the URL of a Vega or Vega-Lite schema, for example https://vega.github.io/schema/vega-lite/v5.json"))
+	case !strings.HasPrefix(schema, vegaSchemaPrefix) && !strings.HasPrefix(schema, vegaLiteSchemaPrefix):
+		errs = append(errs, fmt.Errorf("$schema: %q is neither a Vega nor a Vega-Lite schema", schema))
+	}
+
+	errs = append(errs, validateVegaData("", root)...)
+
+	return errors.Join(errs...)
+}
+
+// validateVegaData walks the spec and validates every data definition. Data can be nested into layers,
+// concatenations and facets, which is why the whole spec is searched.
+func validateVegaData(path string, v any) []error {
+	var errs []error
+	switch val := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := val[k]
+			childPath := joinPath(path, k)
+			if k == "data" {
+				errs = append(errs, validateVegaDataDefinition(childPath, child)...)
+				continue
+			}
+			errs = append(errs, validateVegaData(childPath, child)...)
+		}
+	case []any:
+		for i, child := range val {
+			errs = append(errs, validateVegaData(fmt.Sprintf("%s[%d]", path, i), child)...)
+		}
+	}
+
+	return errs
+}
+
+func validateVegaDataDefinition(path string, data any) []error {
+	switch val := data.(type) {
+	case []any:
+		// Vega: data is a list of data sets
+		var errs []error
+		for i, d := range val {
+			errs = append(errs, validateVegaDataDefinition(fmt.Sprintf("%s[%d]", path, i), d)...)
+		}
+		return errs
+	case map[string]any:
+		url, ok := val["url"].(map[string]any)
+		if !ok {
+			return nil
+		}
+		return validateVegaDataURL(joinPath(path, "url"), url)
+	}
+
+	return nil
+}
+
+func validateVegaDataURL(path string, url map[string]any) []error {
+	if t, ok := url["%type%"].(string); ok && t != "elasticsearch" && t != "opensearch" {
+		// e.g. emsfile, which does not query an index
+		return nil
+	}
+
+	var errs []error
+	index, ok := url["index"].(string)
+	if !ok || strings.TrimSpace(index) == "" {
+		errs = append(errs, fmt.Errorf("%s: must be set to a non-empty string", joinPath(path, "index")))
+	}
+
+	body, hasBody := url["body"]
+	if !hasBody {
+		return errs
+	}
+	bodyMap, ok := body.(map[string]any)
+	if !ok {
+		return append(errs, fmt.Errorf("%s: must be an object, got %s", joinPath(path, "body"), jsonTypeName(body)))
+	}
+	if context, _ := url["%context%"].(bool); context {
+		if _, ok := bodyMap["query"]; ok {
+			errs = append(errs, fmt.Errorf("%s: must not be set if %%context%% is true, the query of the dashboard is used instead", joinPath(path, "body.query")))
+		}
+	}
+
+	return errs
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func jsonTypeName(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,168 @@
+package visualization
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHJSON(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		input   string
+		want    any
+		wantErr bool
+	}{
+		{
+			desc:  "must parse JSON",
+			input: `{"a": [1, "b", true, null], "c": {"d": "e"}}`,
+			want:  map[string]any{"a": []any{float64(1), "b", true, nil}, "c": map[string]any{"d": "e"}},
+		},
+		{
+			desc: "must parse HJSON",
+			input: `{
+  # comment
+  $schema: https://vega.github.io/schema/vega-lite/v5.json
+  // another comment
+  data: {
+    url: {
+      %context%: true
+      index: logs-*
+      body: { size: 0, }
+    }
+  }
+  /* block
+     comment */
+  title: 'quoted, with comma'
+  text:
+    '''
+    first
+    second
+    '''
+}`,
+			want: map[string]any{
+				"$schema": "https://vega.github.io/schema/vega-lite/v5.json",
+				"data": map[string]any{"url": map[string]any{
+					"%context%": true,
+					"index":     "logs-*",
+					"body":      map[string]any{"size": float64(0)},
+				}},
+				"title": "quoted, with comma",
+				"text":  "first\nsecond",
+			},
+		},
+		{
+			desc:  "must parse root object without braces",
+			input: "a: 1\nb: c",
+			want:  map[string]any{"a": float64(1), "b": "c"},
+		},
+		{
+			desc:    "must fail on unterminated object",
+			input:   `{"a": 1`,
+			wantErr: true,
+		},
+		{
+			desc:    "must fail on missing colon",
+			input:   `{"a" 1}`,
+			wantErr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := ParseHJSON(tC.input)
+			if tC.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tC.want) {
+				t.Errorf("expected %+v but got %+v", tC.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateVegaSpec(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		spec    string
+		wantErr string
+	}{
+		{
+			desc: "must accept valid Vega-Lite spec",
+			spec: `{"$schema": "https://vega.github.io/schema/vega-lite/v5.json", "data": {"url": {"%context%": true, "index": "logs-*", "body": {"size": 0}}}, "mark": "line"}`,
+		},
+		{
+			desc: "must accept valid Vega spec with data list",
+			spec: `{"$schema": "https://vega.github.io/schema/vega/v5.json", "data": [{"name": "table", "url": {"index": "logs-*"}}]}`,
+		},
+		{
+			desc: "must accept external data urls",
+			spec: `{"$schema": "https://vega.github.io/schema/vega-lite/v5.json", "data": {"url": "https://example.com/data.json"}}`,
+		},
+		{
+			desc:    "must reject invalid syntax",
+			spec:    `{"$schema": "https://vega.github.io/schema/vega-lite/v5.json",, }`,
+			wantErr: "neither valid JSON nor HJSON",
+		},
+		{
+			desc:    "must reject missing $schema",
+			spec:    `{"mark": "line"}`,
+			wantErr: "$schema: must be set",
+		},
+		{
+			desc:    "must reject foreign $schema",
+			spec:    `{"$schema": "https://json-schema.org/draft/2020-12/schema"}`,
+			wantErr: "neither a Vega nor a Vega-Lite schema",
+		},
+		{
+			desc:    "must reject data url without index",
+			spec:    `{"$schema": "https://vega.github.io/schema/vega-lite/v5.json", "layer": [{"data": {"url": {"body": {}}}}]}`,
+			wantErr: "layer[0].data.url.index: must be set",
+		},
+		{
+			desc:    "must reject body which is no object",
+			spec:    `{"$schema": "https://vega.github.io/schema/vega/v5.json", "data": [{"url": {"index": "logs-*", "body": "size: 0"}}]}`,
+			wantErr: "data[0].url.body: must be an object",
+		},
+		{
+			desc:    "must reject query in body with context",
+			spec:    `{"$schema": "https://vega.github.io/schema/vega-lite/v5.json", "data": {"url": {"%context%": true, "index": "logs-*", "body": {"query": {}}}}}`,
+			wantErr: "data.url.body.query: must not be set",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := ValidateVegaSpec(tC.spec)
+			if tC.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error but got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tC.wantErr) {
+				t.Errorf("expected error containing %q but got %v", tC.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package visualization
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+const (
+	savedObjectType  = "visualization"
+	indexPatternType = "index-pattern"
+	searchType       = "search"
+
+	indexRefName       = "kibanaSavedObjectMeta.searchSourceJSON.index"
+	savedSearchRefName = "search_0"
+	filterRefNameFmt   = "kibanaSavedObjectMeta.searchSourceJSON.filter[%d].meta.index"
+
+	DefaultQueryLanguage = "kuery"
+)
+
+// Aggregation is a single entry of the aggs-array inside visState.
+type Aggregation struct {
+	ID      string         `json:"id"`
+	Enabled bool           `json:"enabled"`
+	Type    string         `json:"type"`
+	Schema  string         `json:"schema,omitempty"`
+	Params  map[string]any `json:"params"`
+}
+
+// Visualization is the structured representation of a saved object of type visualization.
+// The OpenSearch Dashboards API stores visState, uiStateJSON and searchSourceJSON as
+// stringified JSON, which is what ToSavedObject and FromSavedObject convert from and to.
+type Visualization struct {
+	ID          string
+	Title       string
+	Description string
+	Type        string
+
+	Aggregations []Aggregation
+	Params       map[string]any
+	UIState      map[string]any
+
+	SavedSearchID  string
+	IndexPatternID string
+	Query          string
+	QueryLanguage  string
+	Filters        []any
+}
+
+type visState struct {
+	Title  string         `json:"title"`
+	Type   string         `json:"type"`
+	Aggs   []Aggregation  `json:"aggs"`
+	Params map[string]any `json:"params"`
+}
+
+type searchSource struct {
+	Query        *query `json:"query,omitempty"`
+	Filter       []any  `json:"filter"`
+	IndexRefName string `json:"indexRefName,omitempty"`
+}
+
+type query struct {
+	Query    any    `json:"query"`
+	Language string `json:"language"`
+}
+
+// ToSavedObject assembles the saved object which is sent to the OpenSearch Dashboards API.
+func (v *Visualization) ToSavedObject() (*saved_objects.SavedObjectOSD, error) {
+	if v.SavedSearchID != "" && v.IndexPatternID != "" {
+		return nil, fmt.Errorf("visualization %q can either be based on a saved search or on an index pattern, not both", v.ID)
+	}
+
+	aggs := make([]Aggregation, len(v.Aggregations))
+	copy(aggs, v.Aggregations)
+	for i := range aggs {
+		if aggs[i].Params == nil {
+			aggs[i].Params = map[string]any{}
+		}
+	}
+	params := v.Params
+	if params == nil {
+		params = map[string]any{}
+	}
+	state, err := json.Marshal(visState{Title: v.Title, Type: v.Type, Aggs: aggs, Params: params})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode visState: %w", err)
+	}
+
+	uiState := v.UIState
+	if uiState == nil {
+		uiState = map[string]any{}
+	}
+	uiStateJSON, err := json.Marshal(uiState)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode uiStateJSON: %w", err)
+	}
+
+	var refs []saved_objects.Reference
+	language := v.QueryLanguage
+	if language == "" {
+		language = DefaultQueryLanguage
+	}
+	source := searchSource{Query: &query{Query: v.Query, Language: language}, Filter: []any{}}
+
+	attributes := map[string]any{
+		"title":       v.Title,
+		"description": v.Description,
+		"version":     1,
+		"visState":    string(state),
+		"uiStateJSON": string(uiStateJSON),
+	}
+
+	switch {
+	case v.SavedSearchID != "":
+		attributes["savedSearchRefName"] = savedSearchRefName
+		refs = append(refs, saved_objects.Reference{ID: v.SavedSearchID, Name: savedSearchRefName, Type: searchType})
+	case v.IndexPatternID != "":
+		source.IndexRefName = indexRefName
+		refs = append(refs, saved_objects.Reference{ID: v.IndexPatternID, Name: indexRefName, Type: indexPatternType})
+	}
+
+	for i, f := range v.Filters {
+		filter, ref, err := extractFilterReference(i, f)
+		if err != nil {
+			return nil, err
+		}
+		source.Filter = append(source.Filter, filter)
+		if ref != nil {
+			refs = append(refs, *ref)
+		}
+	}
+
+	searchSourceJSON, err := json.Marshal(source)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode searchSourceJSON: %w", err)
+	}
+	attributes["kibanaSavedObjectMeta"] = map[string]any{"searchSourceJSON": string(searchSourceJSON)}
+
+	if refs == nil {
+		refs = []saved_objects.Reference{}
+	}
+
+	return &saved_objects.SavedObjectOSD{
+		Type: savedObjectType,
+		ID:   v.ID,
+		SavedObjectPostPayload: saved_objects.SavedObjectPostPayload{
+			Attributes: attributes,
+			References: refs,
+		},
+	}, nil
+}
+
+// FromSavedObject parses a visualization fetched from the OpenSearch Dashboards API.
+func FromSavedObject(obj *saved_objects.SavedObjectTF) (*Visualization, error) {
+	if obj.Type != savedObjectType {
+		return nil, fmt.Errorf("saved object %q has type %q, expected %q", obj.ID, obj.Type, savedObjectType)
+	}
+
+	var attributes struct {
+		Title                 string `json:"title"`
+		Description           string `json:"description"`
+		VisState              string `json:"visState"`
+		UIStateJSON           string `json:"uiStateJSON"`
+		SavedSearchRefName    string `json:"savedSearchRefName"`
+		KibanaSavedObjectMeta struct {
+			SearchSourceJSON string `json:"searchSourceJSON"`
+		} `json:"kibanaSavedObjectMeta"`
+	}
+	if err := json.Unmarshal([]byte(obj.Attributes), &attributes); err != nil {
+		return nil, fmt.Errorf("could not decode attributes of visualization %q: %w", obj.ID, err)
+	}
+
+	v := &Visualization{
+		ID:          obj.ID,
+		Title:       attributes.Title,
+		Description: attributes.Description,
+	}
+
+	state := visState{}
+	if err := unmarshalNested(attributes.VisState, &state); err != nil {
+		return nil, fmt.Errorf("could not decode visState of visualization %q: %w", obj.ID, err)
+	}
+	v.Type = state.Type
+	v.Aggregations = state.Aggs
+	v.Params = state.Params
+
+	if err := unmarshalNested(attributes.UIStateJSON, &v.UIState); err != nil {
+		return nil, fmt.Errorf("could not decode uiStateJSON of visualization %q: %w", obj.ID, err)
+	}
+
+	source := searchSource{}
+	if err := unmarshalNested(attributes.KibanaSavedObjectMeta.SearchSourceJSON, &source); err != nil {
+		return nil, fmt.Errorf("could not decode searchSourceJSON of visualization %q: %w", obj.ID, err)
+	}
+	if source.Query != nil {
+		if q, ok := source.Query.Query.(string); ok {
+			v.Query = q
+		}
+		v.QueryLanguage = source.Query.Language
+	}
+
+	refs := make(map[string]string, len(obj.References))
+	for _, ref := range obj.References {
+		refs[ref.Name] = ref.ID
+	}
+	if attributes.SavedSearchRefName != "" {
+		v.SavedSearchID = refs[attributes.SavedSearchRefName]
+	}
+	if source.IndexRefName != "" {
+		v.IndexPatternID = refs[source.IndexRefName]
+	}
+	for _, f := range source.Filter {
+		v.Filters = append(v.Filters, injectFilterReference(f, refs))
+	}
+
+	return v, nil
+}
+
+// extractFilterReference moves meta.index of a filter into a reference, the same way
+// OpenSearch Dashboards does when a visualization is saved in the UI.
+func extractFilterReference(i int, f any) (any, *saved_objects.Reference, error) {
+	filter, ok := f.(map[string]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("filter[%d] must be a JSON object, got %T", i, f)
+	}
+	meta, ok := filter["meta"].(map[string]any)
+	if !ok {
+		return filter, nil, nil
+	}
+	index, ok := meta["index"].(string)
+	if !ok || index == "" {
+		return filter, nil, nil
+	}
+
+	name := fmt.Sprintf(filterRefNameFmt, i)
+	newMeta := make(map[string]any, len(meta))
+	for k, val := range meta {
+		newMeta[k] = val
+	}
+	delete(newMeta, "index")
+	newMeta["indexRefName"] = name
+
+	newFilter := make(map[string]any, len(filter))
+	for k, val := range filter {
+		newFilter[k] = val
+	}
+	newFilter["meta"] = newMeta
+
+	return newFilter, &saved_objects.Reference{ID: index, Name: name, Type: indexPatternType}, nil
+}
+
+func injectFilterReference(f any, refs map[string]string) any {
+	filter, ok := f.(map[string]any)
+	if !ok {
+		return f
+	}
+	meta, ok := filter["meta"].(map[string]any)
+	if !ok {
+		return filter
+	}
+	name, ok := meta["indexRefName"].(string)
+	if !ok {
+		return filter
+	}
+	id, ok := refs[name]
+	if !ok {
+		return filter
+	}
+	delete(meta, "indexRefName")
+	meta["index"] = id
+
+	return filter
+}
+
+func unmarshalNested(s string, v any) error {
+	if s == "" {
+		return nil
+	}
+	//nolint: wrapcheck
+	return json.Unmarshal([]byte(s), v)
+}
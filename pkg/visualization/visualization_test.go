@@ -0,0 +1,134 @@
+package visualization
+
+/*
+Copyright 2022 MOIA GmbH
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/moia-oss/terraform-provider-opensearch-dashboards/pkg/saved_objects"
+)
+
+func TestToSavedObject(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		vis          *Visualization
+		wantErr      bool
+		wantRefs     []saved_objects.Reference
+		wantRefNames map[string]string
+	}{
+		{
+			desc: "must reference the index pattern",
+			vis:  &Visualization{ID: "vis", Title: "vis", Type: "table", IndexPatternID: "pattern"},
+			wantRefs: []saved_objects.Reference{
+				{ID: "pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.index", Type: "index-pattern"},
+			},
+		},
+		{
+			desc: "must reference the saved search",
+			vis:  &Visualization{ID: "vis", Title: "vis", Type: "table", SavedSearchID: "search"},
+			wantRefs: []saved_objects.Reference{
+				{ID: "search", Name: "search_0", Type: "search"},
+			},
+			wantRefNames: map[string]string{"savedSearchRefName": "search_0"},
+		},
+		{
+			desc: "must move the index of filters to the references",
+			vis: &Visualization{ID: "vis", Title: "vis", Type: "table", SavedSearchID: "search", Filters: []any{
+				map[string]any{"meta": map[string]any{"index": "pattern", "key": "level"}},
+			}},
+			wantRefs: []saved_objects.Reference{
+				{ID: "search", Name: "search_0", Type: "search"},
+				{ID: "pattern", Name: "kibanaSavedObjectMeta.searchSourceJSON.filter[0].meta.index", Type: "index-pattern"},
+			},
+			wantRefNames: map[string]string{"savedSearchRefName": "search_0"},
+		},
+		{
+			desc:    "must fail if based on saved search and index pattern",
+			vis:     &Visualization{ID: "vis", Title: "vis", Type: "table", SavedSearchID: "search", IndexPatternID: "pattern"},
+			wantErr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			obj, err := tC.vis.ToSavedObject()
+			if tC.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(obj.References, tC.wantRefs) {
+				t.Errorf("expected references %+v but got %+v", tC.wantRefs, obj.References)
+			}
+			for k, v := range tC.wantRefNames {
+				if obj.Attributes[k] != v {
+					t.Errorf("expected attribute %s to be %q but got %q", k, v, obj.Attributes[k])
+				}
+			}
+			if _, ok := obj.Attributes["visState"].(string); !ok {
+				t.Errorf("visState must be stringified JSON, got %T", obj.Attributes["visState"])
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	vis := &Visualization{
+		ID:    "vis",
+		Title: "errors by level",
+		Type:  "table",
+		Aggregations: []Aggregation{
+			{ID: "1", Enabled: true, Type: "count", Schema: "metric", Params: map[string]any{}},
+			{ID: "2", Enabled: true, Type: "terms", Schema: "bucket", Params: map[string]any{"field": "level", "size": float64(5)}},
+		},
+		Params:         map[string]any{"perPage": float64(10)},
+		UIState:        map[string]any{},
+		IndexPatternID: "pattern",
+		Query:          "level:ERROR",
+		QueryLanguage:  "kuery",
+		Filters: []any{
+			map[string]any{"meta": map[string]any{"index": "other-pattern", "key": "component"}},
+		},
+	}
+
+	obj, err := vis.ToSavedObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	attributes, err := json.Marshal(obj.Attributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FromSavedObject(&saved_objects.SavedObjectTF{
+		Type:       obj.Type,
+		ID:         obj.ID,
+		Attributes: string(attributes),
+		References: obj.References,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, vis) {
+		t.Errorf("expected %+v after round trip but got %+v", vis, got)
+	}
+}
@@ -1,6 +1,7 @@
 resource "opensearch_saved_object" "applications_index_pattern" {
   obj_id = "application-index-pattern"
   type   = "index-pattern"
+  deletion_protection = true
   attributes = jsonencode({
     "timeFieldName" : "@timestamp",
     "title" : "applications-*" }
@@ -173,3 +174,127 @@ resource "opensearch_saved_object" "ref_terraform_provider_test_url" {
 
 }
 
+
+resource "opensearch_visualization" "ref_terraform_provider_test_typed_visualization" {
+  obj_id          = "terraform-provider-test-typed-visualization"
+  title           = "terraform-provider-test-typed-visualization"
+  type            = "table"
+  saved_search_id = opensearch_saved_object.ref_terraform_provider_test_search.obj_id
+  query           = "level:ERROR"
+
+  aggregation {
+    id     = "1"
+    type   = "count"
+    schema = "metric"
+  }
+
+  aggregation {
+    id     = "2"
+    type   = "terms"
+    schema = "bucket"
+    params = {
+      field   = "level"
+      orderBy = "1"
+      order   = "desc"
+    }
+    params_json = jsonencode({
+      size = 5
+    })
+  }
+
+  params = jsonencode({
+    perPage = 10
+  })
+
+  filters = jsonencode([
+    {
+      meta = {
+        index  = opensearch_saved_object.applications_index_pattern.obj_id
+        key    = "component"
+        negate = false
+        type   = "phrase"
+        params = { query = "rts" }
+      }
+      query = { match_phrase = { component = "rts" } }
+    }
+  ])
+}
+
+resource "opensearch_vega_visualization" "ref_terraform_provider_test_vega" {
+  obj_id = "terraform-provider-test-vega"
+  title  = "terraform-provider-test-vega"
+  spec = jsonencode({
+    "$schema" = "https://vega.github.io/schema/vega-lite/v5.json"
+    data = {
+      url = {
+        "%context%"   = true
+        "%timefield%" = "@timestamp"
+        index         = "applications-*"
+        body = {
+          aggs = {
+            time_buckets = {
+              date_histogram = {
+                field          = "@timestamp"
+                fixed_interval = { "%autointerval%" = true }
+              }
+            }
+          }
+          size = 0
+        }
+      }
+      format = { property = "aggregations.time_buckets.buckets" }
+    }
+    mark = "line"
+    encoding = {
+      x = { field = "key", type = "temporal" }
+      y = { field = "doc_count", type = "quantitative" }
+    }
+  })
+}
+
+resource "opensearch_tsvb_visualization" "ref_terraform_provider_test_tsvb" {
+  obj_id        = "terraform-provider-test-tsvb"
+  title         = "terraform-provider-test-tsvb"
+  panel_type    = "timeseries"
+  index_pattern = "applications-*"
+  time_field    = "@timestamp"
+  filter        = "level:ERROR"
+
+  series {
+    id          = "errors-by-component"
+    label       = "errors"
+    split_mode  = "terms"
+    terms_field = "component"
+
+    metric {
+      id   = "count"
+      type = "count"
+    }
+  }
+}
+
+resource "opensearch_advanced_settings" "ref_terraform_provider_test_settings" {
+  settings = {
+    "dateFormat:tz"       = jsonencode("Europe/Berlin")
+    "discover:sampleSize" = jsonencode(100)
+    "theme:darkMode"      = jsonencode(true)
+  }
+}
+
+resource "opensearch_saved_objects_ownership" "ref_terraform_provider_test_visualizations" {
+  types     = ["visualization"]
+  id_prefix = "terraform-provider-test-"
+  managed_ids = [
+    opensearch_visualization.ref_terraform_provider_test_typed_visualization.obj_id,
+    opensearch_vega_visualization.ref_terraform_provider_test_vega.obj_id,
+    opensearch_tsvb_visualization.ref_terraform_provider_test_tsvb.obj_id,
+  ]
+  require_managed_marker = true
+  prune                  = true
+}
+
+resource "opensearch_dashboard_copy" "ref_terraform_provider_test_dashboard_copy" {
+  source_dashboard_id = opensearch_saved_object.ref_terraform_provider_test_dashboard.obj_id
+  id_prefix           = "team-a-"
+  title_template      = "{{title}} (team a)"
+}
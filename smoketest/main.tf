@@ -13,4 +13,9 @@ provider "opensearch" {
   base_url = "http://localhost:5601"
   disable_authentication = true
   path_prefix = ""
+  validate_references = "error"
+
+  managed_marker {
+    title_suffix = " [terraform]"
+  }
 }